@@ -0,0 +1,298 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// AdminUser is the account view admins get: the public user fields plus the
+// role and suspension state.
+type AdminUser struct {
+	User
+	Role        string     `json:"role"`
+	SuspendedAt *time.Time `json:"suspended_at"`
+}
+
+type adminUsersPage struct {
+	Users      []AdminUser `json:"users"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+func adminUserFromRow(row database.ListUsersRow) AdminUser {
+	user := AdminUser{
+		User: User{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Email:         row.Email,
+			IsChirpyRed:   row.IsChirpyRed,
+			Handle:        row.Handle.String,
+			DisplayName:   row.DisplayName,
+			Bio:           row.Bio,
+			EmailVerified: row.EmailVerifiedAt.Valid,
+		},
+		Role: row.Role,
+	}
+	if row.SuspendedAt.Valid {
+		user.SuspendedAt = &row.SuspendedAt.Time
+	}
+	return user
+}
+
+// likePattern turns a search term into an ILIKE substring pattern, escaping
+// the wildcard characters so they match literally.
+func likePattern(q string) string {
+	if q == "" {
+		return ""
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q)
+	return "%" + escaped + "%"
+}
+
+// listUsersHandler pages through every account, newest first. The optional
+// q parameter matches a substring of the email, handle or display name.
+func (cfg *apiConfig) listUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	scope := newCursorScope(true, "admin-users", strings.TrimSpace(query.Get("q")))
+	after := firstPageCursor(true)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	rows, err := cfg.db.ListUsers(r.Context(), database.ListUsersParams{
+		Pattern:        likePattern(strings.TrimSpace(query.Get("q"))),
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting users")
+		return
+	}
+
+	page := adminUsersPage{Users: make([]AdminUser, 0, len(rows))}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, scope)
+	}
+	for _, row := range rows {
+		page.Users = append(page.Users, adminUserFromRow(row))
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// targetUserID parses the {userID} path value of an admin action. Admins may
+// not act on their own account, so they cannot lock themselves out.
+func targetUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return uuid.Nil, false
+	}
+	if userID == authFromContext(r.Context()).UserID {
+		respondWithError(w, http.StatusBadRequest, "Admins cannot change their own account")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (cfg *apiConfig) respondWithAdminUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, adminUserFromRow(database.ListUsersRow{
+		ID:              user.ID,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Email:           user.Email,
+		IsChirpyRed:     user.IsChirpyRed,
+		Handle:          user.Handle,
+		DisplayName:     user.DisplayName,
+		Bio:             user.Bio,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Role:            user.Role,
+		SuspendedAt:     user.SuspendedAt,
+	}))
+}
+
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	type setRoleRequest struct {
+		Role role `json:"role"`
+	}
+	var req setRoleRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !req.Role.valid() {
+		respondWithError(w, http.StatusBadRequest, "Role must be user, moderator or admin")
+		return
+	}
+
+	updated, err := cfg.db.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: string(req.Role),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	log.Printf("admin %s set role of user %s to %s", authFromContext(r.Context()).UserID, userID, req.Role)
+
+	cfg.respondWithAdminUser(w, r, userID)
+}
+
+// suspendUserHandler blocks an account from logging in and ends its
+// sessions. Access tokens and personal access tokens already issued stop
+// working immediately, since authentication checks the suspension on every
+// request.
+func (cfg *apiConfig) suspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	updated, err := qtx.SuspendUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error suspending user")
+		return
+	}
+	log.Printf("admin %s suspended user %s", authFromContext(r.Context()).UserID, userID)
+
+	cfg.respondWithAdminUser(w, r, userID)
+}
+
+func (cfg *apiConfig) reactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := cfg.db.ReactivateUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error reactivating user")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	log.Printf("admin %s reactivated user %s", authFromContext(r.Context()).UserID, userID)
+
+	cfg.respondWithAdminUser(w, r, userID)
+}
+
+func (cfg *apiConfig) grantChirpyRedHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpyRed(w, r, true)
+}
+
+func (cfg *apiConfig) revokeChirpyRedHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpyRed(w, r, false)
+}
+
+// setChirpyRed overrides a user's Chirpy Red status outside the Polka
+// webhook, for refunds and comped accounts.
+func (cfg *apiConfig) setChirpyRed(w http.ResponseWriter, r *http.Request, isChirpyRed bool) {
+	userID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := cfg.db.SetChirpyRed(r.Context(), database.SetChirpyRedParams{
+		ID:          userID,
+		IsChirpyRed: isChirpyRed,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	log.Printf("admin %s set Chirpy Red of user %s to %t", authFromContext(r.Context()).UserID, userID, isChirpyRed)
+
+	cfg.respondWithAdminUser(w, r, userID)
+}
+
+// unlockUserHandler clears an account's failed login and 2FA attempts,
+// lifting any backoff or lockout. Throttling of the addresses involved is
+// left in place.
+func (cfg *apiConfig) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+	if err := cfg.loginGuard.Unlock(r.Context(), user.Email, user.ID.String()); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unlocking user")
+		return
+	}
+	log.Printf("admin %s unlocked user %s", authFromContext(r.Context()).UserID, userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
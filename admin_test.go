@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// expectCaller sets up the queries that authenticate a session for user and
+// load their role.
+func expectCaller(mock sqlmock.Sqlmock, user database.User) {
+	mock.ExpectQuery("GetUserAuthStatus").WithArgs(user.ID).WillReturnRows(rowsOf(database.GetUserAuthStatusRow{}))
+	mock.ExpectQuery("GetUserByID").WithArgs(user.ID).WillReturnRows(rowsOf(user))
+}
+
+func newTestUserWithRole(r role) database.User {
+	user := newTestUser()
+	user.Role = string(r)
+	return user
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     role
+		min        role
+		wantStatus int
+	}{
+		{"user on admin route", roleUser, roleAdmin, http.StatusForbidden},
+		{"moderator on admin route", roleModerator, roleAdmin, http.StatusForbidden},
+		{"admin on admin route", roleAdmin, roleAdmin, http.StatusNoContent},
+		{"user on moderator route", roleUser, roleModerator, http.StatusForbidden},
+		{"moderator on moderator route", roleModerator, roleModerator, http.StatusNoContent},
+		{"admin on moderator route", roleAdmin, roleModerator, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			caller := newTestUserWithRole(tt.caller)
+			token, err := cfg.keys.MakeJWT(caller.ID, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT failed: %v", err)
+			}
+			expectCaller(mock, caller)
+
+			w := serve(cfg.requireRole(tt.min, noContent), "GET", "/admin/metrics", token, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestAdminActions(t *testing.T) {
+	admin := newTestUserWithRole(roleAdmin)
+	target := newTestUser()
+	target.ID = uuid.New()
+
+	tests := []struct {
+		name       string
+		pattern    string
+		handler    func(cfg *apiConfig) http.HandlerFunc
+		target     uuid.UUID
+		body       string
+		expect     func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			name:    "suspend ends sessions",
+			pattern: "POST /admin/users/{userID}/suspend",
+			handler: func(cfg *apiConfig) http.HandlerFunc { return cfg.suspendUserHandler },
+			target:  target.ID,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("SuspendUser").WithArgs(target.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("RevokeAllRefreshTokensForUser").WithArgs(target.ID).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				mock.ExpectQuery("GetUserByID").WithArgs(target.ID).WillReturnRows(rowsOf(target))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "suspend self",
+			pattern:    "POST /admin/users/{userID}/suspend",
+			handler:    func(cfg *apiConfig) http.HandlerFunc { return cfg.suspendUserHandler },
+			target:     admin.ID,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "grant Chirpy Red",
+			pattern: "PUT /admin/users/{userID}/chirpy-red",
+			handler: func(cfg *apiConfig) http.HandlerFunc { return cfg.grantChirpyRedHandler },
+			target:  target.ID,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SetChirpyRed").WithArgs(target.ID, true).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("GetUserByID").WithArgs(target.ID).WillReturnRows(rowsOf(target))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "grant Chirpy Red to self",
+			pattern:    "PUT /admin/users/{userID}/chirpy-red",
+			handler:    func(cfg *apiConfig) http.HandlerFunc { return cfg.grantChirpyRedHandler },
+			target:     admin.ID,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "set role of unknown user",
+			pattern: "PUT /admin/users/{userID}/role",
+			handler: func(cfg *apiConfig) http.HandlerFunc { return cfg.setUserRoleHandler },
+			target:  target.ID,
+			body:    `{"role":"moderator"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SetUserRole").WithArgs(target.ID, "moderator").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "set invalid role",
+			pattern:    "PUT /admin/users/{userID}/role",
+			handler:    func(cfg *apiConfig) http.HandlerFunc { return cfg.setUserRoleHandler },
+			target:     target.ID,
+			body:       `{"role":"owner"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			token, err := cfg.keys.MakeJWT(admin.ID, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT failed: %v", err)
+			}
+			expectCaller(mock, admin)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			method, path, _ := strings.Cut(tt.pattern, " ")
+			path = strings.Replace(path, "{userID}", tt.target.String(), 1)
+			h := route(tt.pattern, cfg.requireRole(roleAdmin, tt.handler(cfg)))
+			w := serve(h, method, path, token, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestDeleteChirpAsModerator(t *testing.T) {
+	author := newTestUser()
+	chirp := database.GetChirpRow{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Body:      "hello",
+		UserID:    author.ID,
+	}
+
+	tests := []struct {
+		name       string
+		caller     role
+		wantStatus int
+	}{
+		{"user cannot delete another's chirp", roleUser, http.StatusForbidden},
+		{"moderator deletes any chirp", roleModerator, http.StatusNoContent},
+		{"admin deletes any chirp", roleAdmin, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			caller := newTestUserWithRole(tt.caller)
+			caller.ID = uuid.New()
+			token, err := cfg.keys.MakeJWT(caller.ID, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT failed: %v", err)
+			}
+			mock.ExpectQuery("GetUserAuthStatus").WithArgs(caller.ID).WillReturnRows(rowsOf(database.GetUserAuthStatusRow{}))
+			mock.ExpectQuery("GetChirp").WithArgs(chirp.ID).WillReturnRows(rowsOf(chirp))
+			mock.ExpectQuery("GetUserByID").WithArgs(caller.ID).WillReturnRows(rowsOf(caller))
+			if tt.wantStatus == http.StatusNoContent {
+				mock.ExpectExec("DeleteChirp").WithArgs(chirp.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			h := route("DELETE /api/chirps/{chirpID}", cfg.requireAuth(scopeChirpsWrite, cfg.deleteChirpHandler))
+			w := serve(h, "DELETE", "/api/chirps/"+chirp.ID.String(), token, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *apiConfig) updateChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	type updateChirpRequest struct {
+		Body string `json:"body"`
+	}
+	var req updateChirpRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Body) > maxChirpLength {
+		respondWithError(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	}
+
+	cleaned := cleanChirp(req.Body)
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Lock the row so concurrent edits each snapshot the version they replace.
+	current, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+		return
+	}
+	if current.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "Forbidden")
+		return
+	}
+	if current.RechirpOf.Valid {
+		respondWithError(w, http.StatusBadRequest, "Rechirps cannot be edited")
+		return
+	}
+
+	dbChirp := database.UpdateChirpBodyRow(current)
+	if cleaned != current.Body {
+		if err := qtx.CreateChirpRevision(r.Context(), chirpID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving chirp revision")
+			return
+		}
+		dbChirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:   chirpID,
+			Body: cleaned,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating chirp")
+			return
+		}
+		if err := syncChirpEntities(r.Context(), qtx, chirpID, dbChirp.Body); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error saving chirp entities")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating chirp")
+		return
+	}
+
+	chirp := chirpFromRow(database.GetChirpRow(dbChirp))
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*Chirp{&chirp}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirp)
+}
+
+func (cfg *apiConfig) chirpHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+		return
+	}
+
+	dbRevisions, err := cfg.db.ListChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp history")
+		return
+	}
+
+	resp := struct {
+		Chirp     Chirp           `json:"chirp"`
+		Revisions []ChirpRevision `json:"revisions"`
+	}{
+		Chirp:     chirpFromRow(dbChirp),
+		Revisions: make([]ChirpRevision, 0, len(dbRevisions)),
+	}
+	for _, rev := range dbRevisions {
+		resp.Revisions = append(resp.Revisions, ChirpRevision{
+			ID:         rev.ID,
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), []*Chirp{&resp.Chirp}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
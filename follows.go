@@ -0,0 +1,194 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followsPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You cannot follow yourself")
+		return
+	}
+
+	if _, err := cfg.db.GetUserByID(r.Context(), followeeID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+
+	err = cfg.db.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error following user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	err = cfg.db.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unfollowing user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, false)
+}
+
+func (cfg *apiConfig) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, true)
+}
+
+// listFollows serves one page of either side of the follow graph, newest
+// relationships first.
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, following bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	listing := "followers"
+	if following {
+		listing = "following"
+	}
+	scope := newCursorScope(true, listing, userID.String())
+	after := firstPageCursor(true)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	params := database.ListFollowersParams{
+		UserID:         userID,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageLimit:      limit + 1,
+	}
+	var rows []database.ListFollowersRow
+	if following {
+		var followingRows []database.ListFollowingRow
+		followingRows, err = cfg.db.ListFollowing(r.Context(), database.ListFollowingParams(params))
+		for _, row := range followingRows {
+			rows = append(rows, database.ListFollowersRow(row))
+		}
+	} else {
+		rows, err = cfg.db.ListFollowers(r.Context(), params)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting follows")
+		return
+	}
+
+	page := followsPage{Users: make([]Follow, 0, len(rows))}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.UserID}, scope)
+	}
+	for _, row := range rows {
+		page.Users = append(page.Users, Follow{
+			UserID:     row.UserID,
+			FollowedAt: row.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	query := r.URL.Query()
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	scope := newCursorScope(true, "timeline", userID.String())
+	after := firstPageCursor(true)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	dbChirps, err := cfg.db.ListTimeline(r.Context(), database.ListTimelineParams{
+		FollowerID:     userID,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting timeline")
+		return
+	}
+
+	page := chirpsPage{Chirps: make([]Chirp, 0, len(dbChirps))}
+	if len(dbChirps) > int(limit) {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, scope)
+	}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromRow(database.GetChirpRow(dbChirp)))
+	}
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpPtrs(page.Chirps)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
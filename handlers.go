@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/akigithub888/chirpy/internal/auth"
 	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/mail"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func (cfg *apiConfig) polkaWebhookHandler(w http.ResponseWriter, r *http.Request) {
@@ -60,16 +66,7 @@ func (cfg *apiConfig) polkaWebhookHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
-	tokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenString, cfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
+	userID := authFromContext(r.Context()).UserID
 
 	chirpIDStr := r.PathValue("chirpID")
 
@@ -88,9 +85,18 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Authors delete their own chirps; moderators and admins delete anyone's.
 	if dbChirp.UserID != userID {
-		respondWithError(w, http.StatusForbidden, "Forbidden")
-		return
+		callerRole, err := cfg.effectiveRole(r.Context(), userID)
+		if err != nil && err != sql.ErrNoRows {
+			respondWithError(w, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+		if !callerRole.atLeast(roleModerator) {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		log.Printf("%s %s deleted chirp %s by user %s", callerRole, userID, dbChirp.ID, dbChirp.UserID)
 	}
 	err = cfg.db.DeleteChirp(r.Context(), dbChirp.ID)
 	if err != nil {
@@ -102,16 +108,7 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 
 func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	// 🔐 Authenticate
-	tokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenString, cfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
+	userID := authFromContext(r.Context()).UserID
 
 	// 📥 Decode body
 	type updateUserRequest struct {
@@ -130,6 +127,10 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Email and password required")
 		return
 	}
+	if !mail.ValidAddress(req.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
 
 	// 🔐 Hash password
 	hashedPassword, err := auth.HashPassword(req.Password)
@@ -138,10 +139,39 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	current, err := qtx.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+
 	// 💾 Update user
-	user, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
+	err = qtx.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+		ID:    userID,
+		Email: req.Email,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "Email already taken")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
 		ID:             userID,
-		Email:          req.Email,
 		HashedPassword: hashedPassword,
 	})
 	if err != nil {
@@ -149,24 +179,51 @@ func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// ✉️ A new address has to be verified again
+	emailChanged := req.Email != current.Email
+	var verifyToken string
+	if emailChanged {
+		verifyToken, err = issueVerificationToken(r.Context(), qtx, userID, req.Email)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating verification token")
+			return
+		}
+	}
+
+	user, err := qtx.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	if emailChanged {
+		if err := cfg.sendVerificationEmail(r.Context(), user.Email, verifyToken); err != nil {
+			log.Printf("sending verification email to user %s: %v", user.ID, err)
+		}
+	}
+
 	// ✅ Respond with updated user (no password)
-	resp := struct {
-		ID          uuid.UUID `json:"id"`
-		Email       string    `json:"email"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
-	}{
-		ID:          user.ID,
-		Email:       user.Email,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		IsChirpyRed: user.IsChirpyRed,
+	resp := User{
+		ID:            user.ID,
+		Email:         user.Email,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		IsChirpyRed:   user.IsChirpyRed,
+		Handle:        user.Handle.String,
+		DisplayName:   user.DisplayName,
+		Bio:           user.Bio,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 
 	respondWithJSON(w, http.StatusOK, resp)
 }
 
+// refreshTokenTTL is how long a refresh token stays valid after it is issued.
+const refreshTokenTTL = 60 * 24 * time.Hour
+
 func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	//  Decode request body
 	var req loginRequest
@@ -176,7 +233,23 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//  Look up user by email
+	// Count the attempt as a failure before checking the password, and
+	// refuse to check it while this account or address is backing off from
+	// earlier failures. Refused attempts are not counted, so the lock runs
+	// out however many requests arrive while it holds.
+	_, ip := clientMetadata(r)
+	wait, err := cfg.loginGuard.Attempt(r.Context(), req.Email, ip, time.Now().UTC())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking login attempts")
+		return
+	}
+	if wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+
+	//  Look up user by email. Unknown emails keep their failure too, so the
+	// throttle does not reveal which accounts exist.
 	user, err := cfg.db.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
@@ -190,12 +263,59 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := cfg.loginGuard.PasswordAccepted(r.Context(), ip); err != nil {
+		log.Printf("releasing login attempt for user %s: %v", user.ID, err)
+	}
+	cfg.completeLogin(w, r, user)
+}
+
+func respondTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+	respondWithError(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later")
+}
+
+// completeLogin runs once the user has proven who they are. Accounts with
+// 2FA get a challenge token for /api/login/2fa; everyone else gets a session.
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	totp, err := cfg.db.GetTOTPCredential(r.Context(), user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor settings")
+		return
+	}
+	if err == nil && totp.EnabledAt.Valid {
+		challenge, err := cfg.keys.MakeChallengeJWT(user.ID, twoFactorChallengeTTL)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Could not create challenge token")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]any{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	cfg.startSession(w, r, user)
+}
+
+// startSession issues an access token and a refresh token that starts a new
+// token family, and responds with both. The login is complete by now,
+// second factor included, so the account's failed attempts are cleared.
+func (cfg *apiConfig) startSession(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account suspended")
+		return
+	}
+	if err := cfg.loginGuard.Success(r.Context(), user.Email); err != nil {
+		log.Printf("clearing login failures for user %s: %v", user.ID, err)
+	}
+
 	//  Determine token expiration
 	const maxExpiration = time.Hour
 	expires := maxExpiration
 
 	//  Create JWT
-	token, err := auth.MakeJWT(user.ID, cfg.tokenSecret, expires)
+	token, err := cfg.keys.MakeJWT(user.ID, expires)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not create token")
 		return
@@ -206,14 +326,20 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Could not create refresh token")
 		return
 	}
-	expiresAt := time.Now().UTC().Add(60 * 24 * time.Hour)
+	now := time.Now().UTC()
+	userAgent, ip := clientMetadata(r)
 
-	_, err = cfg.db.CreateRefreshToken(
+	// Each login starts a new token family; rotations stay inside it.
+	err = cfg.db.CreateRefreshToken(
 		r.Context(),
 		database.CreateRefreshTokenParams{
-			Token:     refresh_token,
-			UserID:    user.ID,
-			ExpiresAt: expiresAt,
+			TokenHash:        auth.HashToken(refresh_token),
+			UserID:           user.ID,
+			ExpiresAt:        now.Add(refreshTokenTTL),
+			FamilyID:         uuid.New(),
+			SessionCreatedAt: now,
+			UserAgent:        userAgent,
+			IpAddress:        ip,
 		},
 	)
 	if err != nil {
@@ -242,6 +368,9 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, resp)
 }
 
+// refreshHandler trades a refresh token for a new access token and a new
+// refresh token. The presented token is retired; presenting it again means it
+// was copied, so the whole family descended from that login is revoked.
 func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 	tokenString, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -249,12 +378,27 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshTokenRecord, err := cfg.db.GetUserFromRefreshToken(r.Context(), tokenString)
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not refresh token")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	tokenHash := auth.HashToken(tokenString)
+	refreshTokenRecord, err := qtx.GetUserFromRefreshToken(r.Context(), tokenHash)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
+	if refreshTokenRecord.ReplacedBy.Valid {
+		tx.Rollback()
+		cfg.revokeTokenFamily(w, r, refreshTokenRecord.FamilyID, refreshTokenRecord.ID)
+		return
+	}
+
 	if refreshTokenRecord.RevokedAt.Valid {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -265,14 +409,71 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(refreshTokenRecord.ID, cfg.tokenSecret, time.Hour)
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not create refresh token")
+		return
+	}
+	newTokenHash := auth.HashToken(newRefreshToken)
+	userAgent, ip := clientMetadata(r)
+	err = qtx.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash:        newTokenHash,
+		UserID:           refreshTokenRecord.ID,
+		ExpiresAt:        time.Now().UTC().Add(refreshTokenTTL),
+		FamilyID:         refreshTokenRecord.FamilyID,
+		SessionCreatedAt: refreshTokenRecord.SessionCreatedAt,
+		UserAgent:        userAgent,
+		IpAddress:        ip,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not save refresh token")
+		return
+	}
+
+	// Losing this race means another request rotated the same token first,
+	// which is reuse just the same.
+	rotated, err := qtx.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		TokenHash:  tokenHash,
+		ReplacedBy: sql.NullString{String: newTokenHash, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not rotate refresh token")
+		return
+	}
+	if rotated == 0 {
+		tx.Rollback()
+		cfg.revokeTokenFamily(w, r, refreshTokenRecord.FamilyID, refreshTokenRecord.ID)
+		return
+	}
+
+	accessToken, err := cfg.keys.MakeJWT(refreshTokenRecord.ID, time.Hour)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not create access token")
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not refresh token")
+		return
+	}
 	respondWithJSON(w, http.StatusOK, map[string]string{
-		"token": accessToken,
+		"token":         accessToken,
+		"refresh_token": newRefreshToken,
+	})
+}
+
+// revokeTokenFamily responds to a reused refresh token by revoking every
+// token in its family, then rejects the request.
+func (cfg *apiConfig) revokeTokenFamily(w http.ResponseWriter, r *http.Request, familyID, userID uuid.UUID) {
+	_, err := cfg.db.RevokeRefreshTokenFamily(r.Context(), database.RevokeRefreshTokenFamilyParams{
+		FamilyID: familyID,
+		UserID:   userID,
 	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not revoke refresh tokens")
+		return
+	}
+	log.Printf("refresh token reuse detected for user %s, revoked token family %s", userID, familyID)
+	respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 }
 
 func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
@@ -281,7 +482,7 @@ func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-	err = cfg.db.RevokeRefreshToken(r.Context(), tokenString)
+	err = cfg.db.RevokeRefreshToken(r.Context(), auth.HashToken(tokenString))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Could not revoke refresh token")
 		return
@@ -307,46 +508,141 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
 		return
 	}
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	chirp := chirpFromRow(dbChirp)
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), []*Chirp{&chirp}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
 	}
 	respondWithJSON(w, http.StatusOK, chirp)
 }
 
 func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
 
-	var dbChirps []database.Chirp
-	var err error
+	sortDesc := false
+	switch query.Get("sort") {
+	case "", "asc":
+	case "desc":
+		sortDesc = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid sort, expected asc or desc")
+		return
+	}
+
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid since timestamp")
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid until timestamp")
+		return
+	}
+	if since.Valid && until.Valid && !since.Time.Before(until.Time) {
+		respondWithError(w, http.StatusBadRequest, "since must be before until")
+		return
+	}
 
-	authorIDStr := r.URL.Query().Get("author_id")
-	if authorIDStr != "" {
-		authorID, err := uuid.Parse(authorIDStr)
+	var authorID uuid.NullUUID
+	if authorIDStr := query.Get("author_id"); authorIDStr != "" {
+		authorID.UUID, err = uuid.Parse(authorIDStr)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "Invalid author ID")
 			return
 		}
-		dbChirps, err = cfg.db.GetChirpsByUser(r.Context(), authorID)
-	} else {
-		dbChirps, err = cfg.db.GetChirps(r.Context())
+		authorID.Valid = true
+	}
+
+	scope := newCursorScope(sortDesc, "chirps", uuidFilter(authorID), timeFilter(since), timeFilter(until))
+	after := firstPageCursor(sortDesc)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	// Fetch one extra row so we know whether another page exists.
+	var dbChirps []database.ListChirpsRow
+	switch {
+	case authorID.Valid && sortDesc:
+		var rows []database.ListChirpsByUserDescRow
+		rows, err = cfg.db.ListChirpsByUserDesc(r.Context(), database.ListChirpsByUserDescParams{
+			UserID:         authorID.UUID,
+			AfterCreatedAt: after.CreatedAt,
+			AfterID:        after.ID,
+			Since:          since,
+			Until:          until,
+			PageLimit:      limit + 1,
+		})
+		for _, row := range rows {
+			dbChirps = append(dbChirps, database.ListChirpsRow(row))
+		}
+	case authorID.Valid:
+		var rows []database.ListChirpsByUserRow
+		rows, err = cfg.db.ListChirpsByUser(r.Context(), database.ListChirpsByUserParams{
+			UserID:         authorID.UUID,
+			AfterCreatedAt: after.CreatedAt,
+			AfterID:        after.ID,
+			Since:          since,
+			Until:          until,
+			PageLimit:      limit + 1,
+		})
+		for _, row := range rows {
+			dbChirps = append(dbChirps, database.ListChirpsRow(row))
+		}
+	case sortDesc:
+		var rows []database.ListChirpsDescRow
+		rows, err = cfg.db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AfterCreatedAt: after.CreatedAt,
+			AfterID:        after.ID,
+			Since:          since,
+			Until:          until,
+			PageLimit:      limit + 1,
+		})
+		for _, row := range rows {
+			dbChirps = append(dbChirps, database.ListChirpsRow(row))
+		}
+	default:
+		dbChirps, err = cfg.db.ListChirps(r.Context(), database.ListChirpsParams{
+			AfterCreatedAt: after.CreatedAt,
+			AfterID:        after.ID,
+			Since:          since,
+			Until:          until,
+			PageLimit:      limit + 1,
+		})
 	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
 		return
 	}
 
+	// This endpoint predates pagination and still returns a bare array, so
+	// the next page is linked from a header instead of the body.
 	chirps := make([]Chirp, 0, len(dbChirps))
+	var nextCursor string
+	if len(dbChirps) > int(limit) {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, scope)
+	}
 	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
-		})
+		chirps = append(chirps, chirpFromRow(database.GetChirpRow(dbChirp)))
+	}
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), chirpPtrs(chirps)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	if nextCursor != "" {
+		setNextPageLink(w, r, nextCursor)
 	}
 	respondWithJSON(w, http.StatusOK, chirps)
 
@@ -354,8 +650,11 @@ func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	type createuserRequest struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		Handle      string `json:"handle"`
+		DisplayName string `json:"display_name"`
+		Bio         string `json:"bio"`
 	}
 	var req createuserRequest
 	decoder := json.NewDecoder(r.Body)
@@ -364,25 +663,67 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Error decoding user")
 		return
 	}
+	if !mail.ValidAddress(req.Email) {
+		respondWithError(w, http.StatusBadRequest, "Invalid email address")
+		return
+	}
+	if msg := validateProfile(req.Handle, req.DisplayName, req.Bio); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error hashing password")
+		return
 	}
 
-	dbUser, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating new user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	dbUser, err := qtx.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          req.Email,
 		HashedPassword: hashedPassword,
+		Handle:         sql.NullString{String: req.Handle, Valid: req.Handle != ""},
+		DisplayName:    req.DisplayName,
+		Bio:            req.Bio,
 	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			respondWithError(w, http.StatusConflict, "Email or handle already taken")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error creating new user")
+		return
+	}
+	verifyToken, err := issueVerificationToken(r.Context(), qtx, dbUser.ID, dbUser.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating verification token")
+		return
+	}
+	if err := tx.Commit(); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating new user")
 		return
 	}
+	// The account exists either way; a failed send can be retried through
+	// the resend endpoint.
+	if err := cfg.sendVerificationEmail(r.Context(), dbUser.Email, verifyToken); err != nil {
+		log.Printf("sending verification email to user %s: %v", dbUser.ID, err)
+	}
+
 	user := User{
 		ID:          dbUser.ID,
 		Email:       dbUser.Email,
 		CreatedAt:   dbUser.CreatedAt,
 		UpdatedAt:   dbUser.UpdatedAt,
 		IsChirpyRed: dbUser.IsChirpyRed,
+		Handle:      dbUser.Handle.String,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
 	}
 	respondWithJSON(w, http.StatusCreated, user)
 }
@@ -395,16 +736,12 @@ func readinessHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
 	type createChirpRequest struct {
-		Body string `json:"body"`
+		Body      string        `json:"body"`
+		InReplyTo uuid.NullUUID `json:"in_reply_to"`
+		QuoteOf   uuid.NullUUID `json:"quote_of"`
 	}
-	tokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	userID, err := auth.ValidateJWT(tokenString, cfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	userID := authFromContext(r.Context()).UserID
+	if !cfg.requireVerifiedEmail(w, r, userID) {
 		return
 	}
 
@@ -417,18 +754,52 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(req.Body) > 140 {
+	if len(req.Body) > maxChirpLength {
 		respondWithError(w, http.StatusBadRequest, "Chirp is too long")
 		return
 	}
 
+	if req.InReplyTo.Valid {
+		if _, err := cfg.db.GetChirp(r.Context(), req.InReplyTo.UUID); err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist")
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+			return
+		}
+	}
+
+	if req.QuoteOf.Valid {
+		original, err := cfg.resolveOriginal(r.Context(), req.QuoteOf.UUID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(w, http.StatusBadRequest, "Chirp being quoted does not exist")
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+			return
+		}
+		req.QuoteOf.UUID = original
+	}
+
 	cleaned := cleanChirp(req.Body)
 
-	dbChirp, err := cfg.db.CreateChirp(
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	dbChirp, err := qtx.CreateChirp(
 		r.Context(),
 		database.CreateChirpParams{
-			Body:   cleaned,
-			UserID: userID,
+			Body:      cleaned,
+			UserID:    userID,
+			InReplyTo: req.InReplyTo,
+			QuoteOf:   req.QuoteOf,
 		},
 	)
 
@@ -437,14 +808,20 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	if err := syncChirpEntities(r.Context(), qtx, dbChirp.ID, dbChirp.Body); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving chirp entities")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating chirp")
+		return
 	}
 
+	chirp := chirpFromRow(database.GetChirpRow(dbChirp))
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*Chirp{&chirp}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
 	respondWithJSON(w, http.StatusCreated, chirp)
 }
 
@@ -495,6 +872,52 @@ func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// chirpFromRow converts a chirp row into its API representation. Every chirp
+// query selects the same columns, so other row types convert to GetChirpRow.
+func chirpFromRow(row database.GetChirpRow) Chirp {
+	return Chirp{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		Body:      row.Body,
+		UserID:    row.UserID,
+		InReplyTo: row.InReplyTo,
+		QuoteOf:   newChirpReference(row.QuoteOf),
+		RechirpOf: newChirpReference(row.RechirpOf),
+	}
+}
+
+// hydrateChirps fills in everything on a chirp that lives outside its own
+// row: embedded quote and rechirp originals, then like counts and mentions
+// for the chirps and the originals alike. Each step is a single batched query.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewerID uuid.NullUUID, chirps []*Chirp) error {
+	originals, err := cfg.fillReferences(ctx, chirps)
+	if err != nil {
+		return err
+	}
+	all := append(chirps, originals...)
+	if err := cfg.fillLikes(ctx, viewerID, all); err != nil {
+		return err
+	}
+	return cfg.fillMentions(ctx, all)
+}
+
+// syncChirpEntities re-derives the hashtags and mentions stored alongside a
+// chirp from its body. It runs inside the transaction that writes the chirp.
+func syncChirpEntities(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := syncHashtags(ctx, q, chirpID, body); err != nil {
+		return err
+	}
+	return syncMentions(ctx, q, chirpID, body)
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate
+// value for a unique constraint or index.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 func respondWithError(w http.ResponseWriter, code int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -509,6 +932,8 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	json.NewEncoder(w).Encode(payload)
 }
 
+const maxChirpLength = 140
+
 func cleanChirp(body string) string {
 	badWords := map[string]struct{}{
 		"kerfuffle": {},
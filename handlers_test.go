@@ -0,0 +1,360 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/lockout"
+	"github.com/google/uuid"
+)
+
+func TestRefreshRotation(t *testing.T) {
+	const presented = "presented-refresh-token"
+	userID := uuid.New()
+	familyID := uuid.New()
+	now := time.Now().UTC()
+	record := database.GetUserFromRefreshTokenRow{
+		ID:               userID,
+		Email:            "user@example.com",
+		CreatedAt:        now.Add(-time.Hour),
+		UpdatedAt:        now.Add(-time.Hour),
+		TokenHash:        auth.HashToken(presented),
+		ExpiresAt:        now.Add(time.Hour),
+		FamilyID:         familyID,
+		SessionCreatedAt: now.Add(-time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		record func(database.GetUserFromRefreshTokenRow) database.GetUserFromRefreshTokenRow
+		// rotated is how many rows RotateRefreshToken updates, or -1 if the
+		// handler must not get that far.
+		rotated       int64
+		wantStatus    int
+		wantRevokeAll bool
+	}{
+		{
+			name:       "live token rotates",
+			rotated:    1,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "rotated token revokes its family",
+			record: func(r database.GetUserFromRefreshTokenRow) database.GetUserFromRefreshTokenRow {
+				r.ReplacedBy = sql.NullString{String: auth.HashToken("successor"), Valid: true}
+				return r
+			},
+			rotated:       -1,
+			wantStatus:    http.StatusUnauthorized,
+			wantRevokeAll: true,
+		},
+		{
+			name:          "losing a rotation race revokes the family",
+			rotated:       0,
+			wantStatus:    http.StatusUnauthorized,
+			wantRevokeAll: true,
+		},
+		{
+			name: "revoked token",
+			record: func(r database.GetUserFromRefreshTokenRow) database.GetUserFromRefreshTokenRow {
+				r.RevokedAt = sql.NullTime{Time: now, Valid: true}
+				return r
+			},
+			rotated:    -1,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			record: func(r database.GetUserFromRefreshTokenRow) database.GetUserFromRefreshTokenRow {
+				r.ExpiresAt = now.Add(-time.Minute)
+				return r
+			},
+			rotated:    -1,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			row := record
+			if tt.record != nil {
+				row = tt.record(row)
+			}
+
+			newHash := &captureArg{}
+			mock.ExpectBegin()
+			mock.ExpectQuery("GetUserFromRefreshToken").WithArgs(auth.HashToken(presented)).WillReturnRows(rowsOf(row))
+			if tt.rotated >= 0 {
+				mock.ExpectExec("CreateRefreshToken").
+					WithArgs(newHash, userID, sqlmock.AnyArg(), familyID, row.SessionCreatedAt, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("RotateRefreshToken").
+					WithArgs(auth.HashToken(presented), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, tt.rotated))
+			}
+			if tt.wantStatus == http.StatusOK {
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+			if tt.wantRevokeAll {
+				mock.ExpectExec("RevokeRefreshTokenFamily").
+					WithArgs(familyID, userID).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			}
+
+			w := serve(cfg.refreshHandler, "POST", "/api/refresh", presented, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Token        string `json:"token"`
+				RefreshToken string `json:"refresh_token"`
+			}
+			decodeBody(t, w, &resp)
+			if resp.RefreshToken == "" || resp.RefreshToken == presented {
+				t.Errorf("expected a new refresh token, got %q", resp.RefreshToken)
+			}
+			if newHash.value != auth.HashToken(resp.RefreshToken) {
+				t.Errorf("expected the new token to be stored by its hash")
+			}
+			if gotID, err := cfg.keys.ValidateJWT(resp.Token); err != nil || gotID != userID {
+				t.Errorf("ValidateJWT = %v, %v, want %v", gotID, err, userID)
+			}
+		})
+	}
+}
+
+// TestRefreshTokensStoredHashed checks that raw refresh tokens only ever
+// reach the client; the database sees their digests.
+func TestRefreshTokensStoredHashed(t *testing.T) {
+	user := newTestUser()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, cfg *apiConfig, mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "login stores the digest",
+			run: func(t *testing.T, cfg *apiConfig, mock sqlmock.Sqlmock) {
+				stored := &captureArg{}
+				mock.ExpectQuery("GetUserByEmail").WithArgs(user.Email).WillReturnRows(rowsOf(user))
+				mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("CreateRefreshToken").
+					WithArgs(stored, user.ID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				w := serve(cfg.loginHandler, "POST", "/api/login", "", `{"email":"user@example.com","password":"`+testPassword+`"}`)
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+				}
+				var resp struct {
+					RefreshToken string `json:"refresh_token"`
+				}
+				decodeBody(t, w, &resp)
+				if resp.RefreshToken == "" || stored.value != auth.HashToken(resp.RefreshToken) {
+					t.Errorf("stored %v, want the digest of %q", stored.value, resp.RefreshToken)
+				}
+			},
+		},
+		{
+			name: "revoke looks up the digest",
+			run: func(t *testing.T, cfg *apiConfig, mock sqlmock.Sqlmock) {
+				mock.ExpectExec("RevokeRefreshToken").
+					WithArgs(auth.HashToken("raw-refresh-token")).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				w := serve(cfg.revokeHandler, "POST", "/api/revoke", "raw-refresh-token", "")
+				if w.Code != http.StatusNoContent {
+					t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			tt.run(t, cfg, mock)
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	user := newTestUser()
+	// No backoff before the lockout, so only the lockout is under test.
+	policy := lockout.Policy{
+		LockoutAfter:    3,
+		LockoutDuration: 200 * time.Millisecond,
+		ResetAfter:      time.Hour,
+	}
+
+	type loginStep struct {
+		email    string
+		password string
+		// wantStatus is 429 when the attempt must be refused before the
+		// database is asked anything.
+		wantStatus int
+	}
+	wrong := loginStep{user.Email, "wrong", http.StatusUnauthorized}
+	right := loginStep{user.Email, testPassword, http.StatusOK}
+	refused := loginStep{user.Email, testPassword, http.StatusTooManyRequests}
+
+	tests := []struct {
+		name  string
+		steps []loginStep
+	}{
+		{
+			name:  "failures lock the account, even for the right password",
+			steps: []loginStep{wrong, wrong, wrong, refused, {user.Email, "wrong", http.StatusTooManyRequests}},
+		},
+		{
+			name:  "success clears earlier failures",
+			steps: []loginStep{wrong, wrong, right, wrong, wrong, right},
+		},
+		{
+			name: "unknown accounts lock like real ones",
+			steps: []loginStep{
+				{"nobody@example.com", "guess", http.StatusUnauthorized},
+				{"nobody@example.com", "guess", http.StatusUnauthorized},
+				{"nobody@example.com", "guess", http.StatusUnauthorized},
+				{"nobody@example.com", "guess", http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "account spelling does not dodge the lock",
+			steps: []loginStep{wrong, wrong, wrong, {"  USER@example.com", testPassword, http.StatusTooManyRequests}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			cfg.loginGuard = lockout.NewGuard(lockout.NewMemoryStore(), policy, lockout.DefaultIPPolicy)
+
+			for i, step := range tt.steps {
+				expectLogin(mock, user, step.email, step.password, step.wantStatus)
+				w := serve(cfg.loginHandler, "POST", "/api/login", "",
+					`{"email":"`+step.email+`","password":"`+step.password+`"}`)
+				if w.Code != step.wantStatus {
+					t.Fatalf("step %d: status = %d, want %d: %s", i, w.Code, step.wantStatus, w.Body)
+				}
+				if step.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+					t.Errorf("step %d: expected a Retry-After header", i)
+				}
+			}
+		})
+	}
+}
+
+// TestLoginLockoutExpiresUnderAttack checks that requests refused during a
+// lockout do not extend it, so the owner gets back in once it ends.
+func TestLoginLockoutExpiresUnderAttack(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	user := newTestUser()
+	cfg.loginGuard = lockout.NewGuard(lockout.NewMemoryStore(), lockout.Policy{
+		LockoutAfter:    3,
+		LockoutDuration: 300 * time.Millisecond,
+		ResetAfter:      time.Hour,
+	}, lockout.DefaultIPPolicy)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		return serve(cfg.loginHandler, "POST", "/api/login", "",
+			`{"email":"user@example.com","password":"`+password+`"}`)
+	}
+	// The lockout runs from when the last failure was let in, before its
+	// password was checked.
+	var lockedUntil time.Time
+	for range 3 {
+		expectLogin(mock, user, user.Email, "wrong", http.StatusUnauthorized)
+		lockedUntil = time.Now().Add(300 * time.Millisecond)
+		login("wrong")
+	}
+
+	refused := 0
+	for time.Until(lockedUntil) > 50*time.Millisecond {
+		if w := login("wrong"); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusTooManyRequests, w.Body)
+		}
+		refused++
+		time.Sleep(10 * time.Millisecond)
+	}
+	if refused == 0 {
+		t.Fatal("the lockout ended before any attempt was refused")
+	}
+
+	time.Sleep(time.Until(lockedUntil) + 50*time.Millisecond)
+	expectLogin(mock, user, user.Email, testPassword, http.StatusOK)
+	if w := login(testPassword); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	user := newTestUser()
+	admin := newTestUserWithRole(roleAdmin)
+	admin.ID = uuid.New()
+	cfg.loginGuard = lockout.NewGuard(lockout.NewMemoryStore(), lockout.Policy{
+		LockoutAfter:    3,
+		LockoutDuration: time.Hour,
+		ResetAfter:      time.Hour,
+	}, lockout.DefaultIPPolicy)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		return serve(cfg.loginHandler, "POST", "/api/login", "",
+			`{"email":"user@example.com","password":"`+password+`"}`)
+	}
+	for range 3 {
+		expectLogin(mock, user, user.Email, "wrong", http.StatusUnauthorized)
+		login("wrong")
+	}
+	if w := login(testPassword); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusTooManyRequests, w.Body)
+	}
+
+	token, err := cfg.keys.MakeJWT(admin.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	expectCaller(mock, admin)
+	mock.ExpectQuery("GetUserByID").WithArgs(user.ID).WillReturnRows(rowsOf(user))
+	h := route("POST /admin/users/{userID}/unlock", cfg.requireRole(roleAdmin, cfg.unlockUserHandler))
+	if w := serve(h, "POST", "/admin/users/"+user.ID.String()+"/unlock", token, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("unlock status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+
+	expectLogin(mock, user, user.Email, testPassword, http.StatusOK)
+	if w := login(testPassword); w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+// expectLogin sets up the queries loginHandler makes for an attempt that
+// ends with status. Refused attempts make none.
+func expectLogin(mock sqlmock.Sqlmock, user database.User, email, password string, status int) {
+	if status == http.StatusTooManyRequests {
+		return
+	}
+	lookup := mock.ExpectQuery("GetUserByEmail").WithArgs(email)
+	if email != user.Email {
+		lookup.WillReturnError(sql.ErrNoRows)
+		return
+	}
+	lookup.WillReturnRows(rowsOf(user))
+	if password == testPassword {
+		mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec("CreateRefreshToken").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/entities"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	trendingLimit         = 10
+)
+
+type trendingHashtag struct {
+	Tag  string `json:"tag"`
+	Uses int64  `json:"uses"`
+}
+
+// syncHashtags replaces the stored hashtags of a chirp with the ones found in
+// body.
+func syncHashtags(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := q.DeleteChirpHashtags(ctx, chirpID); err != nil {
+		return err
+	}
+	tags := entities.ExtractHashtags(body)
+	if len(tags) == 0 {
+		return nil
+	}
+	return q.CreateChirpHashtags(ctx, database.CreateChirpHashtagsParams{
+		ChirpID: chirpID,
+		Tags:    tags,
+	})
+}
+
+func (cfg *apiConfig) hashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	scope := newCursorScope(true, "hashtag", tag)
+	after := firstPageCursor(true)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	dbChirps, err := cfg.db.ListChirpsByHashtag(r.Context(), database.ListChirpsByHashtagParams{
+		Tag:            tag,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+
+	page := chirpsPage{Chirps: make([]Chirp, 0, len(dbChirps))}
+	if len(dbChirps) > int(limit) {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, scope)
+	}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromRow(database.GetChirpRow(dbChirp)))
+	}
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), chirpPtrs(page.Chirps)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (cfg *apiConfig) trendingHashtagsHandler(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		var err error
+		window, err = time.ParseDuration(windowStr)
+		if err != nil || window <= 0 || window > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "Invalid window, expected a duration such as 1h up to 168h")
+			return
+		}
+	}
+
+	rows, err := cfg.db.ListTrendingHashtags(r.Context(), database.ListTrendingHashtagsParams{
+		WindowSeconds: int32(window / time.Second),
+		PageLimit:     trendingLimit,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting trending hashtags")
+		return
+	}
+
+	tags := make([]trendingHashtag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, trendingHashtag{
+			Tag:  row.Tag,
+			Uses: row.Uses,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"net/http"
@@ -9,7 +10,6 @@ import (
 	"time"
 
 	"github.com/alexedwards/argon2id"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -29,49 +29,31 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return match, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	now := time.Now().UTC()
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
-		Subject:   userID.String(),
-	}
+// Access tokens and 2FA challenge tokens are signed with the same keys, so
+// the issuer tells them apart and each validator only accepts its own kind.
+const (
+	accessTokenIssuer    = "chirpy"
+	challengeTokenIssuer = "chirpy-2fa"
+)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(tokenSecret))
-	if err != nil {
-		return "", err
-	}
-	return signedToken, nil
+// MakeJWT signs an HS256 access token with tokenSecret. Use a KeySet to sign
+// with asymmetric keys.
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewKeySet(tokenSecret).MakeJWT(userID, expiresIn)
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
-
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		claims,
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("Unexpected signing method")
-			}
-			return []byte(tokenSecret), nil
-		},
-	)
-	if err != nil {
-		return uuid.Nil, err
-	}
-	if !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
-	}
+	return NewKeySet(tokenSecret).ValidateJWT(tokenString)
+}
 
-	userID, err := uuid.Parse(claims.Subject)
-	if err != nil {
-		return uuid.Nil, err
-	}
+// MakeChallengeJWT issues the token a user holds between passing the password
+// check and entering their second factor. It is not an access token.
+func MakeChallengeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewKeySet(tokenSecret).MakeChallengeJWT(userID, expiresIn)
+}
 
-	return userID, nil
+func ValidateChallengeJWT(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
+	return NewKeySet(tokenSecret).ValidateChallengeJWT(tokenString)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -95,6 +77,13 @@ func MakeRefreshToken() (string, error) {
 	return token, nil
 }
 
+// HashToken returns the hex SHA-256 digest of an opaque token, so tokens can
+// be stored and looked up without keeping the secret itself.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	authHeader := headers.Get("Authorization")
 	if authHeader == "" {
@@ -0,0 +1,29 @@
+package auth
+
+import "testing"
+
+func TestHashToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken failed: %v", err)
+	}
+
+	hash := HashToken(token)
+	if hash == token {
+		t.Fatal("expected the hash to differ from the token")
+	}
+	if len(hash) != 64 {
+		t.Errorf("expected a 64 character hex digest, got %d characters", len(hash))
+	}
+	if HashToken(token) != hash {
+		t.Error("expected hashing to be deterministic")
+	}
+
+	other, err := MakeRefreshToken()
+	if err != nil {
+		t.Fatalf("MakeRefreshToken failed: %v", err)
+	}
+	if HashToken(other) == hash {
+		t.Error("expected different tokens to hash differently")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -55,3 +56,40 @@ func TestValidateJWTExpired(t *testing.T) {
 		t.Fatal("expected error for expired token")
 	}
 }
+
+func TestChallengeJWTIsNotAnAccessToken(t *testing.T) {
+	userID := uuid.New()
+
+	challenge, err := MakeChallengeJWT(userID, testSecret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeChallengeJWT failed: %v", err)
+	}
+	if _, err := ValidateJWT(challenge, testSecret); err == nil {
+		t.Fatal("expected a challenge token to be rejected as an access token")
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(challenge, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("parsing challenge token: %v", err)
+	}
+	if typ := parsed.Header["typ"]; typ != "chirpy-2fa+jwt" {
+		t.Errorf("expected a challenge token type, got %v", typ)
+	}
+	gotID, issuedAt, err := ValidateChallengeJWT(challenge, testSecret)
+	if err != nil {
+		t.Fatalf("ValidateChallengeJWT failed: %v", err)
+	}
+	if gotID != userID {
+		t.Errorf("expected userID %v, got %v", userID, gotID)
+	}
+	if since := time.Since(issuedAt); since < 0 || since > time.Minute {
+		t.Errorf("expected the challenge to have been issued just now, got %v", issuedAt)
+	}
+
+	access, err := MakeJWT(userID, testSecret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, _, err := ValidateChallengeJWT(access, testSecret); err == nil {
+		t.Fatal("expected an access token to be rejected as a challenge token")
+	}
+}
@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const minRSAKeyBits = 2048
+
+// KeySet signs and verifies Chirpy JWTs. It signs with one key at a time
+// and verifies against every key it holds, so a retired key keeps working
+// for the tokens it already signed while a new one takes over.
+//
+// Asymmetric keys are addressed by the "kid" header. Tokens without a kid
+// are HS256 tokens signed with the shared secret; those are issued and
+// accepted only until a signing key is added, unless AcceptHS256 keeps them
+// valid through a migration.
+type KeySet struct {
+	hmacSecret  []byte
+	acceptHS256 bool
+	signingKID  string
+	signer      crypto.Signer
+	keys        map[string]verificationKey
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// NewKeySet returns a keyset that signs HS256 tokens with hmacSecret until a
+// signing key is added. An empty secret disables HS256 entirely.
+func NewKeySet(hmacSecret string) *KeySet {
+	return &KeySet{
+		hmacSecret: []byte(hmacSecret),
+		keys:       map[string]verificationKey{},
+	}
+}
+
+// AddSigningKey makes key the key new tokens are signed with, and returns
+// its kid. Ed25519 keys sign with EdDSA and RSA keys with RS256.
+func (ks *KeySet) AddSigningKey(key crypto.Signer) (string, error) {
+	kid, err := ks.AddVerificationKey(key.Public())
+	if err != nil {
+		return "", err
+	}
+	ks.signingKID = kid
+	ks.signer = key
+	return kid, nil
+}
+
+// AcceptHS256 keeps HS256 tokens valid after a signing key is added, so the
+// tokens issued before the switch to asymmetric keys keep working until they
+// expire. Leave it off once they have, or anyone holding the shared secret
+// can still mint tokens.
+func (ks *KeySet) AcceptHS256() {
+	ks.acceptHS256 = true
+}
+
+// AddVerificationKey accepts tokens signed by the private half of pub, and
+// returns its kid. Use it for keys that have been rotated out of signing.
+func (ks *KeySet) AddVerificationKey(pub crypto.PublicKey) (string, error) {
+	method, err := signingMethodFor(pub)
+	if err != nil {
+		return "", err
+	}
+	kid, err := Thumbprint(pub)
+	if err != nil {
+		return "", err
+	}
+	ks.keys[kid] = verificationKey{method: method, public: pub}
+	return kid, nil
+}
+
+func (ks *KeySet) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return ks.makeJWT(userID, accessTokenIssuer, expiresIn)
+}
+
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	userID, _, err := ks.validateJWT(tokenString, accessTokenIssuer)
+	return userID, err
+}
+
+func (ks *KeySet) MakeChallengeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return ks.makeJWT(userID, challengeTokenIssuer, expiresIn)
+}
+
+// ValidateChallengeJWT returns the user a challenge token was issued to and
+// when, so challenges can be withdrawn after the fact.
+func (ks *KeySet) ValidateChallengeJWT(tokenString string) (uuid.UUID, time.Time, error) {
+	return ks.validateJWT(tokenString, challengeTokenIssuer)
+}
+
+// tokenTypes gives each kind of token its own "typ" header, so a verifier
+// that checks it cannot take a challenge token for an access token even if
+// it ignores the issuer.
+var tokenTypes = map[string]string{
+	accessTokenIssuer:    "JWT",
+	challengeTokenIssuer: "chirpy-2fa+jwt",
+}
+
+func (ks *KeySet) makeJWT(userID uuid.UUID, issuer string, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC()
+	claims := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		Subject:   userID.String(),
+	}
+
+	if ks.signer == nil {
+		if len(ks.hmacSecret) == 0 {
+			return "", errors.New("no signing key configured")
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		token.Header["typ"] = tokenTypes[issuer]
+		return token.SignedString(ks.hmacSecret)
+	}
+
+	token := jwt.NewWithClaims(ks.keys[ks.signingKID].method, claims)
+	token.Header["typ"] = tokenTypes[issuer]
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signer)
+}
+
+func (ks *KeySet) validateJWT(tokenString, issuer string) (uuid.UUID, time.Time, error) {
+	claims := &jwt.RegisteredClaims{}
+
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			if typ, _ := token.Header["typ"].(string); typ != tokenTypes[issuer] {
+				return nil, fmt.Errorf("unexpected token type %q", typ)
+			}
+			kid, hasKID := token.Header["kid"].(string)
+			if !hasKID {
+				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || !ks.hs256Valid() {
+					return nil, errors.New("Unexpected signing method")
+				}
+				return ks.hmacSecret, nil
+			}
+			key, ok := ks.keys[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown key ID %q", kid)
+			}
+			if token.Method.Alg() != key.method.Alg() {
+				return nil, errors.New("Unexpected signing method")
+			}
+			return key.public, nil
+		},
+		jwt.WithIssuer(issuer),
+	)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	if !token.Valid || claims.IssuedAt == nil {
+		return uuid.Nil, time.Time{}, errors.New("invalid token")
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+
+	return userID, claims.IssuedAt.Time, nil
+}
+
+func (ks *KeySet) hs256Valid() bool {
+	return len(ks.hmacSecret) > 0 && (ks.signer == nil || ks.acceptHS256)
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys, signing key first. The HS256
+// secret is never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	if ks.signingKID != "" {
+		set.Keys = append(set.Keys, ks.jwk(ks.signingKID))
+	}
+	for _, kid := range slices.Sorted(maps.Keys(ks.keys)) {
+		if kid != ks.signingKID {
+			set.Keys = append(set.Keys, ks.jwk(kid))
+		}
+	}
+	return set
+}
+
+func (ks *KeySet) jwk(kid string) JWK {
+	key := ks.keys[kid]
+	jwk, _ := publicJWK(key.public)
+	jwk.Kid = kid
+	jwk.Use = "sig"
+	jwk.Alg = key.method.Alg()
+	return jwk
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of pub, which Chirpy uses
+// as the key's kid so it never has to be configured by hand.
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	// RFC 7638 hashes only the required members, in lexicographic order.
+	var members any
+	switch jwk.Kty {
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func publicJWK(pub crypto.PublicKey) (JWK, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k)}, nil
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	}
+	return JWK{}, fmt.Errorf("unsupported key type %T", pub)
+}
+
+func signingMethodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", pub)
+}
+
+// ParsePrivateKeyPEM reads an Ed25519 or RSA private key in PKCS #8 form, or
+// an RSA key in PKCS #1 form.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM reads a PKIX public key, as written by
+// "openssl pkey -pubout".
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating Ed25519 key: %v", err)
+	}
+	return priv
+}
+
+func TestKeySetSignsWithKID(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"EdDSA", newEd25519Key(t), "EdDSA"},
+		{"RS256", rsaKey, "RS256"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := NewKeySet("")
+			kid, err := ks.AddSigningKey(tt.key)
+			if err != nil {
+				t.Fatalf("AddSigningKey failed: %v", err)
+			}
+
+			userID := uuid.New()
+			token, err := ks.MakeJWT(userID, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT failed: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatalf("parsing token: %v", err)
+			}
+			if parsed.Header["kid"] != kid {
+				t.Errorf("expected kid %q, got %v", kid, parsed.Header["kid"])
+			}
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("expected alg %s, got %s", tt.alg, parsed.Method.Alg())
+			}
+
+			gotID, err := ks.ValidateJWT(token)
+			if err != nil {
+				t.Fatalf("ValidateJWT failed: %v", err)
+			}
+			if gotID != userID {
+				t.Errorf("expected userID %v, got %v", userID, gotID)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey := newEd25519Key(t)
+	newKey := newEd25519Key(t)
+	userID := uuid.New()
+
+	before := NewKeySet("")
+	if _, err := before.AddSigningKey(oldKey); err != nil {
+		t.Fatalf("AddSigningKey failed: %v", err)
+	}
+	oldToken, err := before.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	// After rotation the old key only verifies.
+	after := NewKeySet("")
+	if _, err := after.AddVerificationKey(oldKey.Public()); err != nil {
+		t.Fatalf("AddVerificationKey failed: %v", err)
+	}
+	if _, err := after.AddSigningKey(newKey); err != nil {
+		t.Fatalf("AddSigningKey failed: %v", err)
+	}
+	if _, err := after.ValidateJWT(oldToken); err != nil {
+		t.Errorf("expected a token signed by the retired key to validate: %v", err)
+	}
+	newToken, err := after.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := before.ValidateJWT(newToken); err == nil {
+		t.Error("expected a keyset without the new key to reject its tokens")
+	}
+
+	if got := len(after.JWKS().Keys); got != 2 {
+		t.Errorf("expected 2 published keys, got %d", got)
+	}
+}
+
+func TestKeySetHS256Fallback(t *testing.T) {
+	userID := uuid.New()
+	legacy, err := MakeJWT(userID, testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	ks := NewKeySet(testSecret)
+	if _, err := ks.ValidateJWT(legacy); err != nil {
+		t.Errorf("expected an HS256 token to validate without a signing key: %v", err)
+	}
+	if _, err := ks.AddSigningKey(newEd25519Key(t)); err != nil {
+		t.Fatalf("AddSigningKey failed: %v", err)
+	}
+	if _, err := ks.ValidateJWT(legacy); err == nil {
+		t.Error("expected an HS256 token to be rejected once a signing key is set")
+	}
+	ks.AcceptHS256()
+	if _, err := ks.ValidateJWT(legacy); err != nil {
+		t.Errorf("expected an HS256 token to validate during a migration: %v", err)
+	}
+
+	noSecret := NewKeySet("")
+	if _, err := noSecret.ValidateJWT(legacy); err == nil {
+		t.Error("expected an HS256 token to be rejected without a secret")
+	}
+}
+
+// TestKeySetRefusesHS256WithSigningKey covers a deployment with a signing key
+// and JWT_ACCEPT_HS256 unset: nothing signed with the shared secret, or
+// dressed up as one of the asymmetric keys, may pass as a token.
+func TestKeySetRefusesHS256WithSigningKey(t *testing.T) {
+	userID := uuid.New()
+	ks := NewKeySet(testSecret)
+	kid, err := ks.AddSigningKey(newEd25519Key(t))
+	if err != nil {
+		t.Fatalf("AddSigningKey failed: %v", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(ks.keys[kid].public)
+	if err != nil {
+		t.Fatalf("marshalling public key: %v", err)
+	}
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    accessTokenIssuer,
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   userID.String(),
+	}
+	tests := []struct {
+		name   string
+		kid    string
+		secret []byte
+	}{
+		{"shared secret", "", []byte(testSecret)},
+		{"shared secret with a kid", kid, []byte(testSecret)},
+		{"public key as HMAC secret", kid, pubDER},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+			token.Header["typ"] = tokenTypes[accessTokenIssuer]
+			if tt.kid != "" {
+				token.Header["kid"] = tt.kid
+			}
+			signed, err := token.SignedString(tt.secret)
+			if err != nil {
+				t.Fatalf("signing token: %v", err)
+			}
+			if _, err := ks.ValidateJWT(signed); err == nil {
+				t.Error("expected an HS256 token to be rejected")
+			}
+		})
+	}
+}
+
+func TestJWKSOmitsSecret(t *testing.T) {
+	ks := NewKeySet(testSecret)
+	if got := len(ks.JWKS().Keys); got != 0 {
+		t.Fatalf("expected no published keys for an HS256-only keyset, got %d", got)
+	}
+
+	key := newEd25519Key(t)
+	kid, err := ks.AddSigningKey(key)
+	if err != nil {
+		t.Fatalf("AddSigningKey failed: %v", err)
+	}
+	keys := ks.JWKS().Keys
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 published key, got %d", len(keys))
+	}
+	jwk := keys[0]
+	if jwk.Kid != kid || jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != "EdDSA" || jwk.Use != "sig" {
+		t.Errorf("unexpected JWK %+v", jwk)
+	}
+}
+
+func TestRejectsSmallRSAKeys(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	if _, err := NewKeySet("").AddSigningKey(small); err == nil {
+		t.Error("expected a 1024-bit RSA key to be rejected")
+	}
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	key := newEd25519Key(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling private key: %v", err)
+	}
+	priv, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM failed: %v", err)
+	}
+
+	der, err = x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("marshalling public key: %v", err)
+	}
+	pub, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM failed: %v", err)
+	}
+
+	privKID, err := Thumbprint(priv.Public())
+	if err != nil {
+		t.Fatalf("Thumbprint failed: %v", err)
+	}
+	pubKID, err := Thumbprint(pub)
+	if err != nil {
+		t.Fatalf("Thumbprint failed: %v", err)
+	}
+	if privKID != pubKID {
+		t.Errorf("expected matching thumbprints, got %s and %s", privKID, pubKID)
+	}
+}
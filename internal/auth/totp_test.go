@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B, base32
+// encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8 digit codes; ours are the last 6 digits of each.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	step, ok := ValidateTOTP(secret, code, now)
+	if !ok {
+		t.Fatal("expected the current code to validate")
+	}
+	if step != now.Unix()/30 {
+		t.Errorf("expected step %d, got %d", now.Unix()/30, step)
+	}
+
+	// One step of drift either way is tolerated, more is not.
+	if _, ok := ValidateTOTP(secret, code, now.Add(30*time.Second)); !ok {
+		t.Error("expected a code from the previous step to validate")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*time.Minute)); ok {
+		t.Error("expected a stale code to be rejected")
+	}
+
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("expected a short code to be rejected")
+	}
+	if _, ok := ValidateTOTP("not base32!", code, now); ok {
+		t.Error("expected an invalid secret to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI(rfc6238Secret, "Chirpy", "user@example.com")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?") {
+		t.Errorf("unexpected URI prefix: %s", uri)
+	}
+	for _, want := range []string{"secret=" + rfc6238Secret, "issuer=Chirpy", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI %s missing %s", uri, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if got := NormalizeRecoveryCode(typed); got != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, got, code)
+		}
+	}
+}
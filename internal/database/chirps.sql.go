@@ -7,8 +7,11 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
@@ -17,32 +20,112 @@ INSERT INTO chirps (
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 )
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    NULL
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quote_of, rechirp_of
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+}
+
+type CreateChirpRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
 }
 
-func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
-	var i Chirp
+func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (CreateChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.QuoteOf,
+	)
+	var i CreateChirpRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.QuoteOf,
+		&i.RechirpOf,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    rechirp_of
+)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quote_of, rechirp_of
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+type CreateRechirpRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (CreateRechirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
+	var i CreateRechirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuoteOf,
+		&i.RechirpOf,
 	)
 	return i, err
 }
@@ -72,50 +155,307 @@ SELECT
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 FROM chirps
 WHERE id = $1
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+type GetChirpRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (GetChirpRow, error) {
 	row := q.db.QueryRowContext(ctx, getChirp, id)
-	var i Chirp
+	var i GetChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuoteOf,
+		&i.RechirpOf,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT
+        parent.id,
+        parent.created_at,
+        parent.updated_at,
+        parent.body,
+        parent.user_id,
+        parent.in_reply_to,
+        parent.quote_of,
+        parent.rechirp_of,
+        1 AS depth
+    FROM chirps child
+    JOIN chirps parent ON parent.id = child.in_reply_to
+    WHERE child.id = $1
+    UNION ALL
+    SELECT
+        c.id,
+        c.created_at,
+        c.updated_at,
+        c.body,
+        c.user_id,
+        c.in_reply_to,
+        c.quote_of,
+        c.rechirp_of,
+        a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+    WHERE a.depth < $2::int
+)
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM ancestors
+ORDER BY depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID
+	MaxDepth int32
+}
+
+type GetChirpAncestorsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE id = $1
+FOR UPDATE
+`
+
+type GetChirpForUpdateRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (GetChirpForUpdateRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i GetChirpForUpdateRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.QuoteOf,
+		&i.RechirpOf,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
+const getChirpReplies = `-- name: GetChirpReplies :many
+WITH RECURSIVE replies AS (
+    SELECT
+        c.id,
+        c.created_at,
+        c.updated_at,
+        c.body,
+        c.user_id,
+        c.in_reply_to,
+        c.quote_of,
+        c.rechirp_of,
+        1 AS depth
+    FROM chirps c
+    WHERE c.in_reply_to = $1::uuid
+    UNION ALL
+    SELECT
+        c.id,
+        c.created_at,
+        c.updated_at,
+        c.body,
+        c.user_id,
+        c.in_reply_to,
+        c.quote_of,
+        c.rechirp_of,
+        r.depth + 1
+    FROM chirps c
+    JOIN replies r ON c.in_reply_to = r.id
+    WHERE r.depth < $2::int
+)
 SELECT
     id,
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM replies
+ORDER BY depth ASC, created_at ASC, id ASC
+`
+
+type GetChirpRepliesParams struct {
+	ChirpID  uuid.UUID
+	MaxDepth int32
+}
+
+type GetChirpRepliesRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetChirpReplies(ctx context.Context, arg GetChirpRepliesParams) ([]GetChirpRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies, arg.ChirpID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpRepliesRow
+	for rows.Next() {
+		var i GetChirpRepliesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 FROM chirps
-ORDER BY created_at ASC
+WHERE id = ANY($1::uuid[])
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsByIDsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]GetChirpsByIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []GetChirpsByIDsRow
 	for rows.Next() {
-		var i Chirp
+		var i GetChirpsByIDsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
 		); err != nil {
 			return nil, err
 		}
@@ -130,33 +470,145 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsByUser = `-- name: GetChirpsByUser :many
+const listChirps = `-- name: ListChirps :many
 SELECT
     id,
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE (created_at, id) > ($1::timestamp, $2::uuid)
+  AND ($3::timestamp IS NULL OR created_at >= $3)
+  AND ($4::timestamp IS NULL OR created_at < $4)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type ListChirpsParams struct {
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	PageLimit      int32
+}
+
+type ListChirpsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) ListChirps(ctx context.Context, arg ListChirpsParams) ([]ListChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirps,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Since,
+		arg.Until,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsRow
+	for rows.Next() {
+		var i ListChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByUser = `-- name: ListChirpsByUser :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 FROM chirps
 WHERE user_id = $1
-ORDER BY created_at ASC
+  AND (created_at, id) > ($2::timestamp, $3::uuid)
+  AND ($4::timestamp IS NULL OR created_at >= $4)
+  AND ($5::timestamp IS NULL OR created_at < $5)
+ORDER BY created_at ASC, id ASC
+LIMIT $6
 `
 
-func (q *Queries) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUser, userID)
+type ListChirpsByUserParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	PageLimit      int32
+}
+
+type ListChirpsByUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) ListChirpsByUser(ctx context.Context, arg ListChirpsByUserParams) ([]ListChirpsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByUser,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Since,
+		arg.Until,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []ListChirpsByUserRow
 	for rows.Next() {
-		var i Chirp
+		var i ListChirpsByUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
 		); err != nil {
 			return nil, err
 		}
@@ -170,3 +622,273 @@ func (q *Queries) GetChirpsByUser(ctx context.Context, userID uuid.UUID) ([]Chir
 	}
 	return items, nil
 }
+
+const listChirpsByUserDesc = `-- name: ListChirpsByUserDesc :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE user_id = $1
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+  AND ($4::timestamp IS NULL OR created_at >= $4)
+  AND ($5::timestamp IS NULL OR created_at < $5)
+ORDER BY created_at DESC, id DESC
+LIMIT $6
+`
+
+type ListChirpsByUserDescParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	PageLimit      int32
+}
+
+type ListChirpsByUserDescRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) ListChirpsByUserDesc(ctx context.Context, arg ListChirpsByUserDescParams) ([]ListChirpsByUserDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByUserDesc,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Since,
+		arg.Until,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsByUserDescRow
+	for rows.Next() {
+		var i ListChirpsByUserDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE (created_at, id) < ($1::timestamp, $2::uuid)
+  AND ($3::timestamp IS NULL OR created_at >= $3)
+  AND ($4::timestamp IS NULL OR created_at < $4)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListChirpsDescParams struct {
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	PageLimit      int32
+}
+
+type ListChirpsDescRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]ListChirpsDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Since,
+		arg.Until,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChirpsDescRow
+	for rows.Next() {
+		var i ListChirpsDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of,
+    ts_rank(search_vector, websearch_to_tsquery('english', $1))::real AS rank
+FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', $1)
+  AND ($2::uuid IS NULL OR user_id = $2)
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $4
+OFFSET $3
+`
+
+type SearchChirpsParams struct {
+	Query      string
+	UserID     uuid.NullUUID
+	PageOffset int32
+	PageLimit  int32
+}
+
+type SearchChirpsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+	Rank      float32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.UserID,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.QuoteOf,
+			&i.RechirpOf,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET
+    body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quote_of, rechirp_of
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+type UpdateChirpBodyRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (UpdateChirpBodyRow, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i UpdateChirpBodyRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.QuoteOf,
+		&i.RechirpOf,
+	)
+	return i, err
+}
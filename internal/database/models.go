@@ -12,27 +12,135 @@ import (
 )
 
 type Chirp struct {
-	ID        uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector interface{}
+	InReplyTo    uuid.NullUUID
+	QuoteOf      uuid.NullUUID
+	RechirpOf    uuid.NullUUID
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type ChirpLike struct {
 	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Email     string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginFailure struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	ExpiresAt     time.Time
+}
+
+type MagicLinkToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	TokenHash  string
+	UserID     uuid.UUID
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RecoveryCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	TokenHash        string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	UserID           uuid.UUID
+	ExpiresAt        time.Time
+	RevokedAt        sql.NullTime
+	FamilyID         uuid.UUID
+	ReplacedBy       sql.NullString
+	SessionCreatedAt time.Time
+	UserAgent        string
+	IpAddress        string
+}
+
+type TotpCredential struct {
+	UserID              uuid.UUID
+	Secret              string
+	CreatedAt           time.Time
+	EnabledAt           sql.NullTime
+	LastUsedStep        int64
+	ChallengesRevokedAt sql.NullTime
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	DisplayName     string
+	Bio             string
+	EmailVerifiedAt sql.NullTime
+	Role            string
+	SuspendedAt     sql.NullTime
 }
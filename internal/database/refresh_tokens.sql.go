@@ -13,30 +13,43 @@ import (
 	"github.com/google/uuid"
 )
 
-const createRefreshToken = `-- name: CreateRefreshToken :one
+const createRefreshToken = `-- name: CreateRefreshToken :exec
 INSERT INTO refresh_tokens (
-    token,
+    token_hash,
     created_at,
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    family_id,
+    session_created_at,
+    user_agent,
+    ip_address
 )
-VALUES ($1, NOW(), NOW(), $2, $3, NULL)
-RETURNING token
+VALUES ($1, NOW(), NOW(), $2, $3, NULL, $4, $5, $6, $7)
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	TokenHash        string
+	UserID           uuid.UUID
+	ExpiresAt        time.Time
+	FamilyID         uuid.UUID
+	SessionCreatedAt time.Time
+	UserAgent        string
+	IpAddress        string
 }
 
-func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (string, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
-	var token string
-	err := row.Scan(&token)
-	return token, err
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createRefreshToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.SessionCreatedAt,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
+	return err
 }
 
 const deleteAllRefreshTokens = `-- name: DeleteAllRefreshTokens :exec
@@ -54,47 +67,165 @@ SELECT
     u.email,
     u.created_at,
     u.updated_at,
-    r.token,
+    r.token_hash,
     r.expires_at,
-    r.revoked_at
+    r.revoked_at,
+    r.family_id,
+    r.replaced_by,
+    r.session_created_at
 FROM refresh_tokens r
 JOIN users u ON r.user_id = u.id
-WHERE r.token = $1
+WHERE r.token_hash = $1
 `
 
 type GetUserFromRefreshTokenRow struct {
-	ID        uuid.UUID
-	Email     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Token     string
-	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	ID               uuid.UUID
+	Email            string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	TokenHash        string
+	ExpiresAt        time.Time
+	RevokedAt        sql.NullTime
+	FamilyID         uuid.UUID
+	ReplacedBy       sql.NullString
+	SessionCreatedAt time.Time
 }
 
-func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (GetUserFromRefreshTokenRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
+func (q *Queries) GetUserFromRefreshToken(ctx context.Context, tokenHash string) (GetUserFromRefreshTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, tokenHash)
 	var i GetUserFromRefreshTokenRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.Token,
+		&i.TokenHash,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.SessionCreatedAt,
 	)
 	return i, err
 }
 
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT
+    family_id,
+    session_created_at,
+    created_at AS last_used_at,
+    expires_at,
+    user_agent,
+    ip_address
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC
+`
+
+type ListActiveSessionsRow struct {
+	FamilyID         uuid.UUID
+	SessionCreatedAt time.Time
+	LastUsedAt       time.Time
+	ExpiresAt        time.Time
+	UserAgent        string
+	IpAddress        string
+}
+
+func (q *Queries) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]ListActiveSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListActiveSessionsRow
+	for rows.Next() {
+		var i ListActiveSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.SessionCreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.UserAgent,
+			&i.IpAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAllRefreshTokensForUser = `-- name: RevokeAllRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllRefreshTokensForUser, userID)
+	return err
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshToken, tokenHash)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokenFamilyParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET replaced_by = $2,
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
+  AND replaced_by IS NULL
+  AND revoked_at IS NULL
+`
+
+type RotateRefreshTokenParams struct {
+	TokenHash  string
+	ReplacedBy sql.NullString
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshToken, arg.TokenHash, arg.ReplacedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
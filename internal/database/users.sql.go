@@ -7,32 +7,43 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
     $2,
-    false
+    false,
+    $3,
+    $4,
+    $5
 )
 RETURNING 
     id,
     created_at,
     updated_at,
     email,
-    is_chirpy_red
+    is_chirpy_red,
+    handle,
+    display_name,
+    bio
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
 }
 
 type CreateUserRow struct {
@@ -41,10 +52,19 @@ type CreateUserRow struct {
 	UpdatedAt   time.Time
 	Email       string
 	IsChirpyRed bool
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+	)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -52,6 +72,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
 	)
 	return i, err
 }
@@ -65,8 +88,26 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const getUserAuthStatus = `-- name: GetUserAuthStatus :one
+SELECT suspended_at, is_chirpy_red
+FROM users
+WHERE id = $1
+`
+
+type GetUserAuthStatusRow struct {
+	SuspendedAt sql.NullTime
+	IsChirpyRed bool
+}
+
+func (q *Queries) GetUserAuthStatus(ctx context.Context, id uuid.UUID) (GetUserAuthStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAuthStatus, id)
+	var i GetUserAuthStatusRow
+	err := row.Scan(&i.SuspendedAt, &i.IsChirpyRed)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, role, suspended_at
 FROM users
 WHERE email = $1
 `
@@ -81,43 +122,375 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, role, suspended_at
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.SuspendedAt,
+	)
+	return i, err
+}
+
+const getUserProfileByHandle = `-- name: GetUserProfileByHandle :one
+SELECT
+    u.id,
+    u.handle,
+    u.display_name,
+    u.bio,
+    u.created_at,
+    u.is_chirpy_red,
+    (SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id) AS following_count,
+    (SELECT COUNT(*) FROM chirps c WHERE c.user_id = u.id) AS chirp_count
+FROM users u
+WHERE LOWER(u.handle) = LOWER($1)
+`
+
+type GetUserProfileByHandleRow struct {
+	ID             uuid.UUID
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	CreatedAt      time.Time
+	IsChirpyRed    bool
+	FollowerCount  int64
+	FollowingCount int64
+	ChirpCount     int64
+}
+
+func (q *Queries) GetUserProfileByHandle(ctx context.Context, handle string) (GetUserProfileByHandleRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfileByHandle, handle)
+	var i GetUserProfileByHandleRow
+	err := row.Scan(
+		&i.ID,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.IsChirpyRed,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.ChirpCount,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :one
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, handle
+FROM users
+WHERE LOWER(handle) = ANY($1::text[])
+`
+
+type GetUsersByHandlesRow struct {
+	ID     uuid.UUID
+	Handle sql.NullString
+}
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]GetUsersByHandlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByHandlesRow
+	for rows.Next() {
+		var i GetUsersByHandlesRow
+		if err := rows.Scan(&i.ID, &i.Handle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, email_verified_at, role, suspended_at
+FROM users
+WHERE (
+    $1::text = ''
+    OR email ILIKE $1
+    OR handle ILIKE $1
+    OR display_name ILIKE $1
+  )
+  AND (created_at, id) < ($2::timestamp, $3::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListUsersParams struct {
+	Pattern        string
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	PageLimit      int32
+}
+
+type ListUsersRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	DisplayName     string
+	Bio             string
+	EmailVerifiedAt sql.NullTime
+	Role            string
+	SuspendedAt     sql.NullTime
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Pattern,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.EmailVerifiedAt,
+			&i.Role,
+			&i.SuspendedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE users
+SET
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND email = $2
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reactivateUser = `-- name: ReactivateUser :execrows
+UPDATE users
+SET
+    suspended_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reactivateUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setChirpyRed = `-- name: SetChirpyRed :execrows
+UPDATE users
+SET
+    is_chirpy_red = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetChirpyRedParams struct {
+	ID          uuid.UUID
+	IsChirpyRed bool
+}
+
+func (q *Queries) SetChirpyRed(ctx context.Context, arg SetChirpyRedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setChirpyRed, arg.ID, arg.IsChirpyRed)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.ID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const suspendUser = `-- name: SuspendUser :execrows
+UPDATE users
+SET
+    suspended_at = COALESCE(suspended_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, suspendUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :exec
 UPDATE users
 SET
     email = $2,
-    hashed_password = $3,
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, email, created_at, updated_at, is_chirpy_red
 `
 
-type UpdateUserParams struct {
+type UpdateUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserEmail, arg.ID, arg.Email)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
 	ID             uuid.UUID
-	Email          string
 	HashedPassword string
 }
 
-type UpdateUserRow struct {
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, is_chirpy_red, handle, display_name, bio, email_verified_at
+`
+
+type UpdateUserProfileParams struct {
 	ID          uuid.UUID
-	Email       string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	IsChirpyRed bool
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+}
+
+type UpdateUserProfileRow struct {
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	DisplayName     string
+	Bio             string
+	EmailVerifiedAt sql.NullTime
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
-	var i UpdateUserRow
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+	)
+	var i UpdateUserProfileRow
 	err := row.Scan(
 		&i.ID,
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,58 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxHashtagLength caps how many runes of a tag are kept; longer tags are
+// ignored rather than truncated.
+const MaxHashtagLength = 50
+
+// ExtractHashtags returns the distinct, lower-cased tags written as #tag in
+// body, in order of first appearance. A tag must start at the beginning of the
+// body or after a character that cannot be part of a tag, so "a#b" and URL
+// fragments are not picked up.
+func ExtractHashtags(body string) []string {
+	var tags []string
+	seen := map[string]struct{}{}
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isTagRune(runes[i-1])) {
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && isTagRune(runes[j]) {
+			j++
+		}
+		tag := strings.ToLower(string(runes[i+1 : j]))
+		i = j - 1
+		if tag == "" || len([]rune(tag)) > MaxHashtagLength || !hasLetter(tag) {
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeHashtag lower-cases a tag taken from a URL and strips a leading #.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func hasLetter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
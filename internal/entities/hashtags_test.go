@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"none", "just chirping", nil},
+		{"single", "hello #Golang", []string{"golang"}},
+		{"dedupe case-insensitive", "#go and #GO and #Go", []string{"go"}},
+		{"punctuation ends tag", "#one, #two! (#three)", []string{"one", "two", "three"}},
+		{"mid-word ignored", "email a#b or c#", nil},
+		{"numbers only ignored", "we're #1", nil},
+		{"underscores and digits", "#web_3 #2024goals", []string{"web_3", "2024goals"}},
+		{"unicode", "#café time", []string{"café"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractHashtags(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractHashtags(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package entities
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinHandleLength = 3
+	MaxHandleLength = 30
+)
+
+// Mention is an @handle found in a chirp body. Start and End are offsets in
+// characters (runes), not bytes; Start points at the '@' and End is exclusive.
+type Mention struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// ExtractMentions returns every @handle in body in order of appearance.
+// Handles are returned as written; callers compare them case-insensitively.
+func ExtractMentions(body string) []Mention {
+	var mentions []Mention
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && (isHandleRune(runes[i-1]) || runes[i-1] == '@')) {
+			continue
+		}
+		start := i
+		end := i + 1
+		for end < len(runes) && isHandleRune(runes[end]) {
+			end++
+		}
+		i = end - 1
+		// A handle running straight into another '@' is part of an address.
+		if end < len(runes) && runes[end] == '@' {
+			continue
+		}
+		handle := string(runes[start+1 : end])
+		if !ValidHandle(handle) {
+			continue
+		}
+		mentions = append(mentions, Mention{Handle: handle, Start: start, End: end})
+	}
+	return mentions
+}
+
+// ValidHandle reports whether s is an acceptable user handle: ASCII letters,
+// digits and underscores, between MinHandleLength and MaxHandleLength long.
+func ValidHandle(s string) bool {
+	n := utf8.RuneCountInString(s)
+	if n < MinHandleLength || n > MaxHandleLength {
+		return false
+	}
+	for _, r := range s {
+		if !isHandleRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeHandle returns the form handles are compared in.
+func NormalizeHandle(s string) string {
+	return strings.ToLower(strings.TrimPrefix(s, "@"))
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Mention
+	}{
+		{"none", "no mentions here", nil},
+		{"start", "@alice hi", []Mention{{Handle: "alice", Start: 0, End: 6}}},
+		{"two", "hey @bob and @Carol_99!", []Mention{
+			{Handle: "bob", Start: 4, End: 8},
+			{Handle: "Carol_99", Start: 13, End: 22},
+		}},
+		{"email ignored", "mail me at bob@example.com", nil},
+		{"too short", "@al is not a handle", nil},
+		{"rune offsets", "héllo @dave", []Mention{{Handle: "dave", Start: 6, End: 11}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractMentions(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractMentions(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	for _, handle := range []string{"bob", "alice_01", "ABC"} {
+		if !ValidHandle(handle) {
+			t.Errorf("ValidHandle(%q) = false, want true", handle)
+		}
+	}
+	for _, handle := range []string{"", "ab", "has space", "émile", "this_handle_is_far_too_long_to_use"} {
+		if ValidHandle(handle) {
+			t.Errorf("ValidHandle(%q) = true, want false", handle)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package lockout throttles repeated failed logins. Every failure is counted
+// against a key (an account, a client IP or a user's second factor); past a few free attempts each
+// further failure doubles the wait before the next try, and enough of them
+// lock the key out for a while.
+package lockout
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Record is the failure history of one key.
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Store persists failure records. Every login attempt that gets to check a
+// password is reserved up front as a failure, and Reserve must count it only
+// if the key's record is still the one the attempt was judged against, in one
+// atomic step, so concurrent attempts cannot all be let in on the same
+// history. Implementations must forget a key once it has gone the resetAfter
+// passed to its last Reserve without another attempt: Get then returns the
+// zero Record.
+type Store interface {
+	Get(ctx context.Context, key string, now time.Time) (Record, error)
+	// Reserve counts an attempt at now against key if its record is still
+	// prev, and reports whether it did.
+	Reserve(ctx context.Context, key string, prev Record, now time.Time, resetAfter time.Duration) (bool, error)
+	// Release takes back one reserved attempt that turned out to succeed.
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy sets how quickly a key is slowed down and locked out.
+type Policy struct {
+	// FreeAttempts is the number of failures allowed without any delay.
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure past FreeAttempts. It
+	// doubles with every further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAfter failures lock the key for LockoutDuration.
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// ResetAfter is how long a key must go without failures before its
+	// history is forgotten. It should be at least LockoutDuration.
+	ResetAfter time.Duration
+}
+
+// DefaultAccountPolicy applies to a single account.
+var DefaultAccountPolicy = Policy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    10,
+	LockoutDuration: 15 * time.Minute,
+	ResetAfter:      time.Hour,
+}
+
+// DefaultIPPolicy applies to a client address. It is looser than the account
+// policy because many users can share one address behind a NAT.
+var DefaultIPPolicy = Policy{
+	FreeAttempts:    10,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    50,
+	LockoutDuration: 15 * time.Minute,
+	ResetAfter:      time.Hour,
+}
+
+// Delay returns how long after the latest of failures consecutive failures
+// the next attempt must wait.
+func (p Policy) Delay(failures int) time.Duration {
+	if failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Guard tracks failures per account and per IP address.
+type Guard struct {
+	store   Store
+	account Policy
+	ip      Policy
+}
+
+func NewGuard(store Store, account, ip Policy) *Guard {
+	return &Guard{store: store, account: account, ip: ip}
+}
+
+func accountKey(account string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func secondFactorKey(user string) string {
+	return "2fa:" + user
+}
+
+// Attempt reserves an attempt to log in to account from ip and returns how
+// long the caller must wait before trying, or zero if it may check the
+// password now. An allowed attempt counts as a failure until Success takes
+// it back, so a burst of parallel requests cannot all get in before any of
+// them is recorded. A refused attempt counts against nothing; otherwise
+// anyone who knows an email could keep its account locked for good.
+func (g *Guard) Attempt(ctx context.Context, account, ip string, now time.Time) (time.Duration, error) {
+	_, wait, err := g.reserve(ctx, ipKey(ip), g.ip, now)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	// The address goes first: if the account turns the attempt away, taking
+	// the reservation back from the address leaves the account untouched.
+	_, wait, err = g.reserve(ctx, accountKey(account), g.account, now)
+	if err != nil || wait > 0 {
+		if releaseErr := g.store.Release(ctx, ipKey(ip)); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+		return wait, err
+	}
+	return 0, nil
+}
+
+// reserve counts an attempt against key unless p makes it wait. It returns
+// the record the attempt was judged against and the wait, which is zero if
+// the attempt was counted.
+func (g *Guard) reserve(ctx context.Context, key string, p Policy, now time.Time) (Record, time.Duration, error) {
+	for {
+		prev, err := g.store.Get(ctx, key, now)
+		if err != nil {
+			return Record{}, 0, err
+		}
+		if wait := p.wait(prev, now); wait > 0 {
+			return prev, wait, nil
+		}
+		ok, err := g.store.Reserve(ctx, key, prev, now, p.ResetAfter)
+		if err != nil {
+			return Record{}, 0, err
+		}
+		if ok {
+			return prev, 0, nil
+		}
+		// Another attempt changed the record first; judge this one again.
+	}
+}
+
+// wait returns how long an attempt at now must wait given the failures
+// before it.
+func (p Policy) wait(prev Record, now time.Time) time.Duration {
+	if prev.Failures == 0 {
+		return 0
+	}
+	until := prev.LastFailure.Add(p.Delay(prev.Failures))
+	if !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+// PasswordAccepted takes back the attempt from ip once its password turned
+// out to be right. Only that one attempt is taken back, so an attacker cannot
+// wipe their own record by logging in to an account they control between
+// guesses. The account keeps its failures until Success, since a login with
+// a second factor has not succeeded yet.
+func (g *Guard) PasswordAccepted(ctx context.Context, ip string) error {
+	return g.store.Release(ctx, ipKey(ip))
+}
+
+// Success clears the account's failures once a login has fully succeeded.
+func (g *Guard) Success(ctx context.Context, account string) error {
+	return g.store.Reset(ctx, accountKey(account))
+}
+
+// AttemptSecondFactor reserves an attempt at user's second factor, counted
+// under the account policy on a key of its own, so a stolen password cannot
+// be used to guess codes indefinitely. It returns the wait like Attempt, and
+// whether failing this allowed attempt locks the key out, at which point the
+// caller should withdraw any challenges the user still holds.
+func (g *Guard) AttemptSecondFactor(ctx context.Context, user string, now time.Time) (time.Duration, bool, error) {
+	prev, wait, err := g.reserve(ctx, secondFactorKey(user), g.account, now)
+	if err != nil {
+		return 0, false, err
+	}
+	return wait, wait == 0 && prev.Failures+1 >= g.account.LockoutAfter, nil
+}
+
+// SecondFactorSuccess clears user's failed second-factor attempts.
+func (g *Guard) SecondFactorSuccess(ctx context.Context, user string) error {
+	return g.store.Reset(ctx, secondFactorKey(user))
+}
+
+// Unlock clears the failures of an account and of its user's second factor,
+// lifting any backoff or lockout.
+func (g *Guard) Unlock(ctx context.Context, account, user string) error {
+	if err := g.store.Reset(ctx, accountKey(account)); err != nil {
+		return err
+	}
+	return g.store.Reset(ctx, secondFactorKey(user))
+}
@@ -0,0 +1,327 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:    2,
+	BaseDelay:       time.Second,
+	MaxDelay:        8 * time.Second,
+	LockoutAfter:    8,
+	LockoutDuration: 10 * time.Minute,
+	ResetAfter:      time.Hour,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := testPolicy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestGuardBackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), testPolicy, DefaultIPPolicy)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	attempt := func(want time.Duration) {
+		t.Helper()
+		got, err := guard.Attempt(ctx, "user@example.com", "203.0.113.7", now)
+		if err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("Attempt = %v, want %v", got, want)
+		}
+	}
+
+	attempt(0)
+	attempt(0)
+	attempt(0)
+	attempt(time.Second)
+
+	now = now.Add(time.Second)
+	attempt(0)
+	attempt(2 * time.Second)
+
+	// The refused attempt did not count, so the wait does not grow.
+	now = now.Add(time.Second)
+	attempt(time.Second)
+
+	now = now.Add(time.Second)
+	attempt(0)
+
+	for range 3 {
+		now = now.Add(time.Minute)
+		attempt(0)
+	}
+	attempt(10 * time.Minute)
+
+	now = now.Add(10 * time.Minute)
+	attempt(0)
+}
+
+func TestGuardRefusedAttemptsDoNotExtendLockout(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), testPolicy, DefaultIPPolicy)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for range testPolicy.LockoutAfter {
+		now = now.Add(time.Minute)
+		if _, err := guard.Attempt(ctx, "user@example.com", "203.0.113.7", now); err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+	}
+	lockedUntil := now.Add(testPolicy.LockoutDuration)
+
+	// An attacker keeps guessing from another address for the whole lockout.
+	for ; now.Before(lockedUntil); now = now.Add(30 * time.Second) {
+		wait, err := guard.Attempt(ctx, "user@example.com", "198.51.100.1", now)
+		if err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+		if want := lockedUntil.Sub(now); wait != want {
+			t.Fatalf("Attempt at %v = %v, want %v", now, wait, want)
+		}
+	}
+
+	wait, err := guard.Attempt(ctx, "user@example.com", "192.0.2.1", lockedUntil)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != 0 {
+		t.Errorf("expected the owner to get in once the lock expired, got wait %v", wait)
+	}
+}
+
+func TestGuardConcurrentAttempts(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), testPolicy, Policy{FreeAttempts: 100, LockoutAfter: 100})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// However many requests race, only the free attempts and the first one
+	// past them get to check a password.
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			wait, err := guard.Attempt(ctx, "user@example.com", "203.0.113.7", now)
+			if err != nil {
+				t.Errorf("Attempt failed: %v", err)
+				return
+			}
+			if wait == 0 {
+				allowed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != int32(testPolicy.FreeAttempts+1) {
+		t.Errorf("expected %d attempts to get through, got %d", testPolicy.FreeAttempts+1, got)
+	}
+}
+
+func TestGuardAccountKeyIgnoresCase(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), testPolicy, DefaultIPPolicy)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, email := range []string{"User@Example.com", "user@example.com", " USER@example.com"} {
+		if _, err := guard.Attempt(ctx, email, "198.51.100.1", now); err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+	}
+	wait, err := guard.Attempt(ctx, "user@example.com", "192.0.2.1", now)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != time.Second {
+		t.Errorf("expected failures from every spelling to count, got wait %v", wait)
+	}
+}
+
+func TestGuardPerIP(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), DefaultAccountPolicy, testPolicy)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Spraying one guess across many accounts still trips the IP limit.
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if _, err := guard.Attempt(ctx, email, "203.0.113.7", now); err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+	}
+	wait, err := guard.Attempt(ctx, "d@example.com", "203.0.113.7", now)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != time.Second {
+		t.Errorf("expected the IP to be slowed down, got wait %v", wait)
+	}
+
+	wait, err = guard.Attempt(ctx, "e@example.com", "203.0.113.8", now)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != 0 {
+		t.Errorf("expected another IP to be unaffected, got wait %v", wait)
+	}
+}
+
+func TestGuardSuccessAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), testPolicy, testPolicy)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for range testPolicy.LockoutAfter {
+		now = now.Add(time.Minute)
+		if _, err := guard.Attempt(ctx, "user@example.com", "203.0.113.7", now); err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+	}
+	if err := guard.Unlock(ctx, "user@example.com", "user-1"); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	wait, err := guard.Attempt(ctx, "user@example.com", "192.0.2.1", now)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != 0 {
+		t.Errorf("expected the account to be unlocked, got wait %v", wait)
+	}
+
+	// Logging in successfully from another address leaves this one locked.
+	if err := guard.PasswordAccepted(ctx, "192.0.2.1"); err != nil {
+		t.Fatalf("PasswordAccepted failed: %v", err)
+	}
+	if err := guard.Success(ctx, "user@example.com"); err != nil {
+		t.Fatalf("Success failed: %v", err)
+	}
+	wait, err = guard.Attempt(ctx, "other@example.com", "203.0.113.7", now)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != testPolicy.LockoutDuration {
+		t.Errorf("expected the IP to stay locked, got wait %v", wait)
+	}
+}
+
+func TestGuardSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	guard := NewGuard(NewMemoryStore(), testPolicy, testPolicy)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	attempt := func(wantWait time.Duration, wantLast bool) {
+		t.Helper()
+		wait, last, err := guard.AttemptSecondFactor(ctx, "user-1", now)
+		if err != nil {
+			t.Fatalf("AttemptSecondFactor failed: %v", err)
+		}
+		if wait != wantWait || last != wantLast {
+			t.Errorf("AttemptSecondFactor = %v, %v, want %v, %v", wait, last, wantWait, wantLast)
+		}
+	}
+
+	for i := range testPolicy.LockoutAfter {
+		now = now.Add(time.Minute)
+		attempt(0, i == testPolicy.LockoutAfter-1)
+	}
+	attempt(testPolicy.LockoutDuration, false)
+
+	// Codes are counted apart from passwords.
+	wait, err := guard.Attempt(ctx, "user@example.com", "203.0.113.7", now)
+	if err != nil {
+		t.Fatalf("Attempt failed: %v", err)
+	}
+	if wait != 0 {
+		t.Errorf("expected the password attempt to be unaffected, got wait %v", wait)
+	}
+
+	if err := guard.Unlock(ctx, "user@example.com", "user-1"); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	attempt(0, false)
+	attempt(0, false)
+	attempt(0, false)
+	attempt(time.Second, false)
+
+	if err := guard.SecondFactorSuccess(ctx, "user-1"); err != nil {
+		t.Fatalf("SecondFactorSuccess failed: %v", err)
+	}
+	wait, _, err = guard.AttemptSecondFactor(ctx, "user-1", now)
+	if err != nil {
+		t.Fatalf("AttemptSecondFactor failed: %v", err)
+	}
+	if wait != 0 {
+		t.Errorf("expected success to clear the failures, got wait %v", wait)
+	}
+}
+
+func TestMemoryStoreResetAfter(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	reserve := func(prev Record, now time.Time, want bool) {
+		t.Helper()
+		ok, err := store.Reserve(ctx, "k", prev, now, time.Hour)
+		if err != nil {
+			t.Fatalf("Reserve failed: %v", err)
+		}
+		if ok != want {
+			t.Errorf("Reserve(%+v) = %v, want %v", prev, ok, want)
+		}
+	}
+	get := func(now time.Time, want Record) {
+		t.Helper()
+		got, err := store.Get(ctx, "k", now)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got.Failures != want.Failures || !got.LastFailure.Equal(want.LastFailure) {
+			t.Errorf("Get = %+v, want %+v", got, want)
+		}
+	}
+
+	get(now, Record{})
+	reserve(Record{}, now, true)
+	// A second attempt judged against the empty record lost the race.
+	reserve(Record{}, now, false)
+	get(now, Record{Failures: 1, LastFailure: now})
+
+	later := now.Add(59 * time.Minute)
+	reserve(Record{Failures: 1, LastFailure: now}, later, true)
+	get(later, Record{Failures: 2, LastFailure: later})
+
+	if err := store.Release(ctx, "k"); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	get(later, Record{Failures: 1, LastFailure: later})
+
+	expired := later.Add(time.Hour)
+	get(expired, Record{})
+	reserve(Record{}, expired, true)
+	get(expired, Record{Failures: 1, LastFailure: expired})
+}
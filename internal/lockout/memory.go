@@ -0,0 +1,82 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const minSweepSize = 1024
+
+// MemoryStore keeps failure records in process memory. It suits a single
+// instance and tests; records are lost on restart and not shared between
+// replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	nextSweep int
+}
+
+type memoryEntry struct {
+	record    Record
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}, nextSweep: minSweepSize}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string, now time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return Record{}, nil
+	}
+	return entry.record, nil
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, prev Record, now time.Time, resetAfter time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = memoryEntry{}
+	}
+	if entry.record.Failures != prev.Failures || !entry.record.LastFailure.Equal(prev.LastFailure) {
+		return false, nil
+	}
+	entry.record.Failures++
+	entry.record.LastFailure = now
+	entry.expiresAt = now.Add(resetAfter)
+	s.entries[key] = entry
+
+	// Keys are attacker-chosen, so drop expired ones before the map can grow
+	// without bound.
+	if len(s.entries) >= s.nextSweep {
+		for k, e := range s.entries {
+			if !now.Before(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = max(2*len(s.entries), minSweepSize)
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok && entry.record.Failures > 0 {
+		entry.record.Failures--
+		s.entries[key] = entry
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,70 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/database"
+)
+
+// PostgresStore keeps failure records in the login_failures table, so every
+// replica sees the same counts.
+type PostgresStore struct {
+	db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string, now time.Time) (Record, error) {
+	row, err := s.db.GetLoginFailures(ctx, database.GetLoginFailuresParams{Key: key, Now: now})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return Record{}, nil
+		}
+		return Record{}, err
+	}
+	return Record{Failures: int(row.Failures), LastFailure: row.LastFailureAt}, nil
+}
+
+func (s *PostgresStore) Reserve(ctx context.Context, key string, prev Record, now time.Time, resetAfter time.Duration) (bool, error) {
+	// A key without a record may still have an expired row, which the first
+	// attempt takes over; otherwise the row must be exactly as it was read.
+	var reserved int64
+	var err error
+	if prev.LastFailure.IsZero() {
+		reserved, err = s.db.ReserveFirstLoginAttempt(ctx, database.ReserveFirstLoginAttemptParams{
+			Key:       key,
+			Now:       now,
+			ExpiresAt: now.Add(resetAfter),
+		})
+	} else {
+		reserved, err = s.db.ReserveLoginAttempt(ctx, database.ReserveLoginAttemptParams{
+			Now:           now,
+			ExpiresAt:     now.Add(resetAfter),
+			Key:           key,
+			PrevFailures:  int32(prev.Failures),
+			PrevFailureAt: prev.LastFailure,
+		})
+	}
+	if err != nil {
+		return false, err
+	}
+	return reserved > 0, nil
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	return s.db.ReleaseLoginAttempt(ctx, key)
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.db.DeleteLoginFailures(ctx, key)
+}
+
+// Sweep deletes records that have already expired. Expired rows are ignored
+// anyway; this only keeps the table from growing.
+func (s *PostgresStore) Sweep(ctx context.Context, now time.Time) error {
+	return s.db.DeleteExpiredLoginFailures(ctx, now)
+}
@@ -0,0 +1,127 @@
+// Package mail delivers the transactional email Chirpy sends, such as
+// verification tokens.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a single message.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ValidAddress reports whether s is a bare email address such as
+// "user@example.com", without a display name or angle brackets.
+func ValidAddress(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// SMTPSender sends mail through an SMTP relay, authenticating with PLAIN auth
+// when a username is configured.
+type SMTPSender struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPSender(host, port, username, password, from string) *SMTPSender {
+	return &SMTPSender{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	data, err := buildMessage(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	// smtp.SendMail has no context support, so run it aside and give up
+	// waiting once the request is gone.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(s.host, s.port), auth, s.from, []string{msg.To}, data)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LogSender writes each message to w instead of delivering it. It is meant
+// for local development, where the token can be copied out of the log.
+type LogSender struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+func NewLogSender(w io.Writer, from string) *LogSender {
+	return &LogSender{w: w, from: from}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	data, err := buildMessage(s.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = fmt.Fprintf(s.w, "%s\r\n.\r\n", data)
+	return err
+}
+
+// buildMessage renders msg as an RFC 5322 message. Header values are
+// rejected if they contain line breaks so a crafted address or subject
+// cannot inject extra headers.
+func buildMessage(from string, msg Message, now time.Time) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, errors.New("mail header contains a line break")
+		}
+	}
+	if !ValidAddress(msg.To) {
+		return nil, fmt.Errorf("invalid recipient address %q", msg.To)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return b.Bytes(), nil
+}
@@ -0,0 +1,70 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"user@example.com", true},
+		{"first.last+tag@sub.example.org", true},
+		{"", false},
+		{"not-an-email", false},
+		{"Alice <alice@example.com>", false},
+		{"alice@example.com\r\nBcc: eve@example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidAddress(tt.addr); got != tt.want {
+			t.Errorf("ValidAddress(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestLogSender(t *testing.T) {
+	var buf bytes.Buffer
+	sender := NewLogSender(&buf, "chirpy@example.com")
+
+	err := sender.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Verify your email",
+		Body:    "line one\nline two",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"From: chirpy@example.com\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Verify your email\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSendRejectsHeaderInjection(t *testing.T) {
+	var buf bytes.Buffer
+	sender := NewLogSender(&buf, "chirpy@example.com")
+
+	err := sender.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "hi\r\nBcc: eve@example.com",
+	})
+	if err == nil {
+		t.Fatal("expected an error for a subject containing a line break")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing written, got %q", buf.String())
+	}
+}
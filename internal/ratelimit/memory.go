@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const minSweepSize = 1024
+
+// MemoryStore keeps buckets in process memory, for a single server.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]time.Time
+	nextSweep int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]time.Time{}, nextSweep: minSweepSize}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tat, res := take(s.buckets[key], limit, now)
+	s.buckets[key] = tat
+
+	// A full bucket is the same as no bucket, so those can be dropped
+	// whenever the map has doubled since the last sweep.
+	if len(s.buckets) >= s.nextSweep {
+		for k, t := range s.buckets {
+			if !t.After(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.nextSweep = max(2*len(s.buckets), minSweepSize)
+	}
+	return res, nil
+}
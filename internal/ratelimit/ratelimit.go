@@ -0,0 +1,67 @@
+// Package ratelimit implements token-bucket rate limiting. Each key owns a
+// bucket holding up to Limit.Burst tokens that refills evenly over
+// Limit.Period; every request spends one token and is refused when the
+// bucket is empty.
+//
+// Buckets are tracked with the generic cell rate algorithm, which stores a
+// single timestamp per key (the moment the bucket will be full again)
+// instead of a token count and a refill time, so a shared store can update
+// it in one step.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is the size and refill rate of a bucket.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Result describes the outcome of one request against a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a refused request must wait for a token. It is
+	// zero for allowed requests.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store holds the buckets. Take must spend a token atomically, even when
+// several servers share the store.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Burst)
+}
+
+// take spends a token from a bucket that will be full at tat, and returns
+// the bucket's new full time along with the result.
+func take(tat time.Time, limit Limit, now time.Time) (time.Time, Result) {
+	interval := limit.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(interval)
+	allowAt := newTAT.Add(-limit.Period)
+	if allowAt.After(now) {
+		return tat, Result{
+			Limit:      limit.Burst,
+			RetryAfter: allowAt.Sub(now),
+			Reset:      tat.Sub(now),
+		}
+	}
+	return newTAT, Result{
+		Allowed:   true,
+		Limit:     limit.Burst,
+		Remaining: int((limit.Period - newTAT.Sub(now)) / interval),
+		Reset:     newTAT.Sub(now),
+	}
+}
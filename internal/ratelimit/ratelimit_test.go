@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+var testLimit = Limit{Burst: 5, Period: 5 * time.Second}
+
+// exerciseStore runs the same bucket through a store: a full burst, a
+// refusal, and a refill.
+func exerciseStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.UnixMilli(1_767_225_600_000).UTC()
+
+	for i := range testLimit.Burst {
+		res, err := store.Take(ctx, "alice", testLimit, now)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		if !res.Allowed || res.Remaining != testLimit.Burst-1-i {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", i, res, testLimit.Burst-1-i)
+		}
+	}
+
+	res, err := store.Take(ctx, "alice", testLimit, now)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 5*time.Second {
+		t.Errorf("expected a refusal with 1s retry and 5s reset, got %+v", res)
+	}
+
+	res, err = store.Take(ctx, "bob", testLimit, now)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if !res.Allowed {
+		t.Errorf("expected another key to have its own bucket, got %+v", res)
+	}
+
+	res, err = store.Take(ctx, "alice", testLimit, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected one token after 1s, got %+v", res)
+	}
+
+	res, err = store.Take(ctx, "alice", testLimit, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if !res.Allowed || res.Remaining != testLimit.Burst-1 {
+		t.Errorf("expected a full bucket after a long pause, got %+v", res)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	exerciseStore(t, NewMemoryStore())
+}
+
+// exerciseScriptRounding checks a bucket whose interval is not a whole
+// number of milliseconds, where the script has to round its waits up.
+func exerciseScriptRounding(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	limit := Limit{Burst: 3, Period: time.Second}
+	now := time.UnixMilli(1_000_000)
+
+	for i := range limit.Burst {
+		res, err := store.Take(ctx, "carol", limit, now)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("request %d: expected to be allowed, got %+v", i, res)
+		}
+	}
+	res, err := store.Take(ctx, "carol", limit, now)
+	if err != nil {
+		t.Fatalf("Take failed: %v", err)
+	}
+	if res.Allowed || res.RetryAfter != 334*time.Millisecond || res.Reset != time.Second {
+		t.Errorf("expected a refusal with 334ms retry and 1s reset, got %+v", res)
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	// miniredis runs the script in a real Lua interpreter. Requiring an ACL
+	// user checks that credentials in the URL reach the server.
+	srv := miniredis.RunT(t)
+	srv.RequireUserAuth("chirpy", "s3cret")
+	opts, err := redis.ParseURL("redis://chirpy:s3cret@" + srv.Addr() + "/0")
+	if err != nil {
+		t.Fatalf("ParseURL failed: %v", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	exerciseStore(t, NewRedisStore(client, "chirpy:rl:"))
+	exerciseScriptRounding(t, NewRedisStore(client, "chirpy:rl:"))
+
+	if !srv.Exists("chirpy:rl:alice") {
+		t.Errorf("expected keys to carry the store prefix, got %v", srv.Keys())
+	}
+	// The bucket expires once it would be full again: alice last spent one
+	// token from a full bucket, which refills in one interval.
+	if ttl := srv.TTL("chirpy:rl:alice"); ttl != time.Second {
+		t.Errorf("expected the bucket to expire when full, got TTL %v", ttl)
+	}
+}
+
+// TestRedisStoreServer runs the take script on a real server, in case it
+// differs from miniredis. Point CHIRPY_TEST_REDIS_URL at a
+// disposable Redis or Valkey database to run it; every key it writes
+// expires within seconds.
+func TestRedisStoreServer(t *testing.T) {
+	url := os.Getenv("CHIRPY_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("CHIRPY_TEST_REDIS_URL is not set")
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("ParseURL failed: %v", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	prefix := "chirpy:test:" + strconv.FormatInt(time.Now().UnixNano(), 10) + ":"
+	exerciseStore(t, NewRedisStore(client, prefix))
+	exerciseScriptRounding(t, NewRedisStore(client, prefix))
+}
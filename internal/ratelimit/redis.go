@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScriptSource is take() in Lua, so the read and the write of a bucket
+// happen in one atomic step on the server. Times are in milliseconds; the
+// bucket's key expires as soon as it would be full again.
+const takeScriptSource = `
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+  tat = now
+end
+local new_tat = tat + interval
+local allow_at = new_tat - period
+if allow_at > now then
+  return {0, 0, math.ceil(allow_at - now), math.ceil(tat - now)}
+end
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil(new_tat - now))
+return {1, math.floor((period - (new_tat - now)) / interval), 0, math.ceil(new_tat - now)}
+`
+
+var takeScript = redis.NewScript(takeScriptSource)
+
+// RedisStore keeps buckets in Redis, or any server that speaks its protocol
+// and runs Lua scripts, so every server in a cluster shares them. Clocks are
+// taken from the caller, so servers sharing a store should keep theirs in
+// sync.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore stores buckets under keys starting with prefix.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	// Run sends the script in full only when the server has not cached it.
+	n, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		now.UnixMilli(),
+		float64(limit.interval())/float64(time.Millisecond),
+		limit.Period.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(n) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", n)
+	}
+	return Result{
+		Allowed:    n[0] == 1,
+		Limit:      limit.Burst,
+		Remaining:  int(n[1]),
+		RetryAfter: time.Duration(n[2]) * time.Millisecond,
+		Reset:      time.Duration(n[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,14 @@
+package main
+
+import "net/http"
+
+// jwksHandler publishes the public keys that verify Chirpy access tokens so
+// other services can check them without the signing secret. The same keys
+// sign the short-lived tokens handed out between the password and 2FA steps
+// of a login, which carry iss "chirpy-2fa" and typ "chirpy-2fa+jwt"; a
+// service must require iss "chirpy" and typ "JWT" before it treats a token
+// as an access token.
+func (cfg *apiConfig) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.keys.JWKS())
+}
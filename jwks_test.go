@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newTestEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	return key
+}
+
+func TestJWKSHandler(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	retired := newTestEd25519Key(t)
+	retiredKID, err := cfg.keys.AddVerificationKey(retired.Public())
+	if err != nil {
+		t.Fatalf("AddVerificationKey failed: %v", err)
+	}
+	signingKID, err := cfg.keys.AddSigningKey(newTestEd25519Key(t))
+	if err != nil {
+		t.Fatalf("AddSigningKey failed: %v", err)
+	}
+
+	w := serve(cfg.jwksHandler, "GET", "/.well-known/jwks.json", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Errorf("expected the key set to be cacheable")
+	}
+	body := w.Body.String()
+	if strings.Contains(body, `"d"`) {
+		t.Fatalf("expected only public keys, got %s", body)
+	}
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Alg string `json:"alg"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	decodeBody(t, w, &set)
+	if len(set.Keys) != 2 || set.Keys[0].Kid != signingKID || set.Keys[1].Kid != retiredKID {
+		t.Fatalf("expected the signing key then the retired one, got %+v", set.Keys)
+	}
+
+	// Another service verifies an access token with nothing but the
+	// published key.
+	userID := uuid.New()
+	token, err := cfg.keys.MakeJWT(userID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	published := map[string][]byte{}
+	for _, k := range set.Keys {
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			t.Fatalf("decoding x of %s failed: %v", k.Kid, err)
+		}
+		published[k.Kid] = x
+	}
+	claims := jwt.RegisteredClaims{}
+	_, err = jwt.ParseWithClaims(token, &claims, func(tok *jwt.Token) (any, error) {
+		kid, _ := tok.Header["kid"].(string)
+		return ed25519.PublicKey(published[kid]), nil
+	}, jwt.WithValidMethods([]string{"EdDSA"}), jwt.WithIssuer("chirpy"))
+	if err != nil {
+		t.Fatalf("verifying with the published key failed: %v", err)
+	}
+	if claims.Subject != userID.String() {
+		t.Errorf("subject = %q, want %q", claims.Subject, userID)
+	}
+}
+
+func TestRequireAuthKeyRotation(t *testing.T) {
+	userID := uuid.New()
+	retired := newTestEd25519Key(t)
+	current := newTestEd25519Key(t)
+
+	// sign returns a token from a keyset that signs with key, or with the
+	// shared secret when key is nil.
+	sign := func(t *testing.T, key ed25519.PrivateKey) string {
+		t.Helper()
+		ks := auth.NewKeySet(testSecret)
+		if key != nil {
+			if _, err := ks.AddSigningKey(key); err != nil {
+				t.Fatalf("AddSigningKey failed: %v", err)
+			}
+		}
+		token, err := ks.MakeJWT(userID, time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT failed: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name        string
+		key         ed25519.PrivateKey
+		acceptHS256 bool
+		wantStatus  int
+	}{
+		{name: "current signing key", key: current, wantStatus: http.StatusNoContent},
+		{name: "retired key still verifies", key: retired, wantStatus: http.StatusNoContent},
+		{name: "unknown key", key: newTestEd25519Key(t), wantStatus: http.StatusUnauthorized},
+		// Once a signing key is configured, the shared secret no longer
+		// mints tokens unless JWT_ACCEPT_HS256 says so.
+		{name: "HS256 after the switch", key: nil, wantStatus: http.StatusUnauthorized},
+		{name: "HS256 during the migration", key: nil, acceptHS256: true, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			if _, err := cfg.keys.AddVerificationKey(retired.Public()); err != nil {
+				t.Fatalf("AddVerificationKey failed: %v", err)
+			}
+			if _, err := cfg.keys.AddSigningKey(current); err != nil {
+				t.Fatalf("AddSigningKey failed: %v", err)
+			}
+			if tt.acceptHS256 {
+				cfg.keys.AcceptHS256()
+			}
+			if tt.wantStatus == http.StatusNoContent {
+				mock.ExpectQuery("GetUserAuthStatus").WithArgs(userID).
+					WillReturnRows(rowsOf(database.GetUserAuthStatusRow{}))
+			}
+
+			w := serve(cfg.requireAuth(scopeChirpsRead, noContent), "GET", "/api/timeline", sign(t, tt.key), "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
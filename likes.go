@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type likedChirp struct {
+	Chirp
+	LikedAt time.Time `json:"liked_at"`
+}
+
+type likedChirpsPage struct {
+	Chirps     []likedChirp `json:"chirps"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// fillLikes sets LikeCount and LikedByMe on every chirp with a single query.
+func (cfg *apiConfig) fillLikes(ctx context.Context, viewerID uuid.NullUUID, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	stats, err := cfg.db.GetChirpLikeStats(ctx, database.GetChirpLikeStatsParams{
+		ViewerID: viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]database.GetChirpLikeStatsRow, len(stats))
+	for _, stat := range stats {
+		byID[stat.ChirpID] = stat
+	}
+	for _, chirp := range chirps {
+		stat := byID[chirp.ID]
+		chirp.LikeCount = stat.LikeCount
+		chirp.LikedByMe = stat.LikedByMe
+	}
+	return nil
+}
+
+// chirpPtrs adapts a slice of chirps for fillLikes.
+func chirpPtrs(chirps []Chirp) []*Chirp {
+	ptrs := make([]*Chirp, 0, len(chirps))
+	for i := range chirps {
+		ptrs = append(ptrs, &chirps[i])
+	}
+	return ptrs
+}
+
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	if _, err := cfg.db.GetChirp(r.Context(), chirpID); err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+		return
+	}
+
+	err = cfg.db.CreateChirpLike(r.Context(), database.CreateChirpLikeParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error liking chirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	err = cfg.db.DeleteChirpLike(r.Context(), database.DeleteChirpLikeParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error unliking chirp")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listLikedChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	scope := newCursorScope(true, "likes", userID.String())
+	after := firstPageCursor(true)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	rows, err := cfg.db.ListLikedChirps(r.Context(), database.ListLikedChirpsParams{
+		UserID:         userID,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting liked chirps")
+		return
+	}
+
+	page := likedChirpsPage{Chirps: make([]likedChirp, 0, len(rows))}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.LikedAt, ID: last.ID}, scope)
+	}
+	for _, row := range rows {
+		page.Chirps = append(page.Chirps, likedChirp{
+			Chirp: Chirp{
+				ID:        row.ID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				Body:      row.Body,
+				UserID:    row.UserID,
+				InReplyTo: row.InReplyTo,
+				QuoteOf:   newChirpReference(row.QuoteOf),
+				RechirpOf: newChirpReference(row.RechirpOf),
+			},
+			LikedAt: row.LikedAt,
+		})
+	}
+	chirps := make([]*Chirp, 0, len(page.Chirps))
+	for i := range page.Chirps {
+		chirps = append(chirps, &page.Chirps[i].Chirp)
+	}
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const magicLinkTTL = 15 * time.Minute
+
+// requestMagicLinkHandler behaves like forgotPasswordHandler: it always
+// answers 202 and does the work in the background, so it reveals nothing
+// about which addresses have accounts.
+func (cfg *apiConfig) requestMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	type magicLinkRequest struct {
+		Email string `json:"email"`
+	}
+	var req magicLinkRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("looking up user for magic link: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	go cfg.sendMagicLink(user.ID, user.Email)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendMagicLink replaces any outstanding magic link tokens for the user and
+// mails a link carrying the new one. Failures are only logged.
+func (cfg *apiConfig) sendMagicLink(userID uuid.UUID, email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := cfg.db.DeleteMagicLinkTokens(ctx, userID); err != nil {
+		log.Printf("clearing magic link tokens for user %s: %v", userID, err)
+		return
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("creating magic link token: %v", err)
+		return
+	}
+	err = cfg.db.CreateMagicLinkToken(ctx, database.CreateMagicLinkTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(magicLinkTTL),
+	})
+	if err != nil {
+		log.Printf("saving magic link token for user %s: %v", userID, err)
+		return
+	}
+
+	// The link opens login/magic/index.html, served under APP_URL, which
+	// posts the token to verifyMagicLinkHandler when the user confirms.
+	link := cfg.appURL + "/login/magic/?token=" + url.QueryEscape(token)
+	err = cfg.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Your Chirpy login link",
+		Body: fmt.Sprintf(
+			"Use this link to log in to Chirpy:\n\n%s\n\nThe link works once and expires in %d minutes. If you did not ask for it, you can ignore this email.\n",
+			link, int(magicLinkTTL.Minutes()),
+		),
+	})
+	if err != nil {
+		log.Printf("sending magic link to user %s: %v", userID, err)
+	}
+}
+
+// verifyMagicLinkHandler exchanges a magic link token for the same response
+// loginHandler gives after a correct password.
+func (cfg *apiConfig) verifyMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	type verifyMagicLinkRequest struct {
+		Token string `json:"token"`
+	}
+	var req verifyMagicLinkRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" {
+		respondWithError(w, http.StatusBadRequest, "Token required")
+		return
+	}
+
+	record, err := cfg.db.ConsumeMagicLinkToken(r.Context(), auth.HashToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error verifying token")
+		return
+	}
+	if time.Now().UTC().After(record.ExpiresAt) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), record.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+		return
+	}
+	cfg.completeLogin(w, r, user)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/mail"
+)
+
+// chanMailer hands every message it is asked to send to the test.
+type chanMailer chan mail.Message
+
+func (m chanMailer) Send(ctx context.Context, msg mail.Message) error {
+	m <- msg
+	return nil
+}
+
+func TestRequestMagicLink(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	sent := make(chanMailer, 1)
+	cfg.mailer = sent
+	user := newTestUser()
+
+	// Unknown addresses get the same answer and no mail.
+	mock.ExpectQuery("GetUserByEmail").WithArgs("nobody@example.com").WillReturnError(sql.ErrNoRows)
+	w := serve(cfg.requestMagicLinkHandler, "POST", "/api/login/magic", "", `{"email":"nobody@example.com"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body)
+	}
+
+	stored := &captureArg{}
+	mock.ExpectQuery("GetUserByEmail").WithArgs(user.Email).WillReturnRows(rowsOf(user))
+	mock.ExpectExec("DeleteMagicLinkTokens").WithArgs(user.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CreateMagicLinkToken").WithArgs(stored, user.ID, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	w = serve(cfg.requestMagicLinkHandler, "POST", "/api/login/magic", "", `{"email":"user@example.com"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body)
+	}
+
+	var msg mail.Message
+	select {
+	case msg = <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no magic link was sent")
+	}
+	if msg.To != user.Email {
+		t.Errorf("sent to %q, want %q", msg.To, user.Email)
+	}
+	_, rawLink, _ := strings.Cut(msg.Body, cfg.appURL+"/login/magic/?token=")
+	token, err := url.QueryUnescape(strings.Fields(rawLink)[0])
+	if err != nil {
+		t.Fatalf("reading token from link failed: %v", err)
+	}
+	if stored.value != auth.HashToken(token) {
+		t.Errorf("expected the token to be stored by its hash")
+	}
+}
+
+func TestVerifyMagicLink(t *testing.T) {
+	const token = "magic-link-token"
+	user := newTestUser()
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name       string
+		body       string
+		expect     func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantTokens bool
+	}{
+		{
+			name: "fresh token logs in",
+			body: `{"token":"` + token + `"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("ConsumeMagicLinkToken").WithArgs(auth.HashToken(token)).
+					WillReturnRows(rowsOf(database.ConsumeMagicLinkTokenRow{UserID: user.ID, ExpiresAt: now.Add(time.Minute)}))
+				mock.ExpectQuery("GetUserByID").WithArgs(user.ID).WillReturnRows(rowsOf(user))
+				mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnError(sql.ErrNoRows)
+				mock.ExpectExec("CreateRefreshToken").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusOK,
+			wantTokens: true,
+		},
+		{
+			name: "used or unknown token",
+			body: `{"token":"` + token + `"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("ConsumeMagicLinkToken").WithArgs(auth.HashToken(token)).WillReturnError(sql.ErrNoRows)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			body: `{"token":"` + token + `"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("ConsumeMagicLinkToken").WithArgs(auth.HashToken(token)).
+					WillReturnRows(rowsOf(database.ConsumeMagicLinkTokenRow{UserID: user.ID, ExpiresAt: now.Add(-time.Minute)}))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing token",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			w := serve(cfg.verifyMagicLinkHandler, "POST", "/api/login/magic/verify", "", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if !tt.wantTokens {
+				return
+			}
+			var resp struct {
+				Token        string `json:"token"`
+				RefreshToken string `json:"refresh_token"`
+			}
+			decodeBody(t, w, &resp)
+			if gotID, err := cfg.keys.ValidateJWT(resp.Token); err != nil || gotID != user.ID {
+				t.Errorf("ValidateJWT = %v, %v, want %v", gotID, err, user.ID)
+			}
+			if resp.RefreshToken == "" {
+				t.Errorf("expected a refresh token")
+			}
+		})
+	}
+}
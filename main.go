@@ -1,40 +1,77 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/akigithub888/chirpy/internal/auth"
 	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/lockout"
+	"github.com/akigithub888/chirpy/internal/mail"
+	"github.com/akigithub888/chirpy/internal/ratelimit"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	dbConn         *sql.DB
 	platform       string
-	tokenSecret    string
+	keys           *auth.KeySet
 	polkaKey       string
+	mailer         mail.Sender
+	appURL         string
+	loginGuard     *lockout.Guard
+	rateLimiter    ratelimit.Store
 }
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Handle        string    `json:"handle,omitempty"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	EmailVerified bool      `json:"email_verified"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Body      string          `json:"body"`
+	UserID    uuid.UUID       `json:"user_id"`
+	InReplyTo uuid.NullUUID   `json:"in_reply_to"`
+	QuoteOf   *chirpReference `json:"quote_of"`
+	RechirpOf *chirpReference `json:"rechirp_of"`
+	LikeCount int64           `json:"like_count"`
+	LikedByMe bool            `json:"liked_by_me"`
+	Mentions  []chirpMention  `json:"mentions"`
+}
+
+// chirpReference points at a quoted or rechirped chirp. Chirp is filled in
+// when the original still exists; otherwise Deleted marks a tombstone.
+type chirpReference struct {
+	ID      uuid.UUID `json:"id"`
+	Deleted bool      `json:"deleted"`
+	Chirp   *Chirp    `json:"chirp,omitempty"`
+}
+
+type chirpsPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
 
 type loginRequest struct {
@@ -54,26 +91,89 @@ func main() {
 		log.Fatal("Unable to open a connection to database")
 	}
 	dbQueries := database.New(db)
+	keys, err := newKeySet()
+	if err != nil {
+		log.Fatalf("Unable to load JWT keys: %v", err)
+	}
+	mailer, err := newMailer()
+	if err != nil {
+		log.Fatalf("Unable to configure mail: %v", err)
+	}
+	rateLimiter, err := newRateLimitStore()
+	if err != nil {
+		log.Fatalf("Unable to configure rate limiting: %v", err)
+	}
 	cfg := &apiConfig{
 		db:          dbQueries,
+		dbConn:      db,
 		platform:    os.Getenv("PLATFORM"),
-		tokenSecret: os.Getenv("SECRET_KEY"),
 		polkaKey:    os.Getenv("POLKA_KEY"),
+		keys:        keys,
+		mailer:      mailer,
+		appURL:      os.Getenv("APP_URL"),
+		loginGuard:  newLoginGuard(dbQueries),
+		rateLimiter: rateLimiter,
+	}
+	if cfg.appURL == "" {
+		cfg.appURL = "http://localhost:8080/app"
 	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /api/healthz", readinessHandler)
-	mux.HandleFunc("GET /admin/metrics", cfg.metricsHandler)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.jwksHandler)
+	mux.HandleFunc("GET /admin/metrics", cfg.requireRole(roleAdmin, cfg.metricsHandler))
+	mux.HandleFunc("GET /admin/users", cfg.requireRole(roleAdmin, cfg.listUsersHandler))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", cfg.requireRole(roleAdmin, cfg.setUserRoleHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/suspend", cfg.requireRole(roleAdmin, cfg.suspendUserHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/reactivate", cfg.requireRole(roleAdmin, cfg.reactivateUserHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/unlock", cfg.requireRole(roleAdmin, cfg.unlockUserHandler))
+	mux.HandleFunc("PUT /admin/users/{userID}/chirpy-red", cfg.requireRole(roleAdmin, cfg.grantChirpyRedHandler))
+	mux.HandleFunc("DELETE /admin/users/{userID}/chirpy-red", cfg.requireRole(roleAdmin, cfg.revokeChirpyRedHandler))
+	mux.HandleFunc("DELETE /admin/chirps/{chirpID}", cfg.requireRole(roleModerator, cfg.deleteChirpHandler))
 	mux.HandleFunc("POST /admin/reset", cfg.resetHandler)
-	mux.HandleFunc("POST /api/users", cfg.createUserHandler)
-	mux.HandleFunc("POST /api/chirps", cfg.createChirpHandler)
-	mux.HandleFunc("GET /api/chirps", cfg.getChirpsHandler)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirpHandler)
-	mux.HandleFunc("POST /api/login", cfg.loginHandler)
-	mux.HandleFunc("POST /api/refresh", cfg.refreshHandler)
-	mux.HandleFunc("POST /api/revoke", cfg.revokeHandler)
-	mux.HandleFunc("PUT /api/users", cfg.updateUserHandler)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirpHandler)
+	mux.HandleFunc("POST /api/users", cfg.rateLimit(authBudget, cfg.createUserHandler))
+	mux.HandleFunc("POST /api/chirps", cfg.requireAuth(scopeChirpsWrite, cfg.rateLimit(chirpBudget, cfg.createChirpHandler)))
+	mux.HandleFunc("GET /api/chirps", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.getChirpsHandler)))
+	mux.HandleFunc("GET /api/chirps/search", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.searchChirpsHandler)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.getChirpHandler)))
+	mux.HandleFunc("POST /api/login", cfg.rateLimit(authBudget, cfg.loginHandler))
+	mux.HandleFunc("POST /api/login/2fa", cfg.rateLimit(authBudget, cfg.loginTwoFactorHandler))
+	mux.HandleFunc("POST /api/login/magic", cfg.rateLimit(authBudget, cfg.requestMagicLinkHandler))
+	mux.HandleFunc("POST /api/login/magic/verify", cfg.rateLimit(authBudget, cfg.verifyMagicLinkHandler))
+	mux.HandleFunc("POST /api/refresh", cfg.rateLimit(authBudget, cfg.refreshHandler))
+	mux.HandleFunc("POST /api/revoke", cfg.rateLimit(authBudget, cfg.revokeHandler))
+	mux.HandleFunc("POST /api/password/forgot", cfg.rateLimit(authBudget, cfg.forgotPasswordHandler))
+	mux.HandleFunc("POST /api/password/reset", cfg.rateLimit(authBudget, cfg.resetPasswordHandler))
+	mux.HandleFunc("GET /api/sessions", cfg.requireAuth(scopeSession, cfg.rateLimit(readBudget, cfg.listSessionsHandler)))
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.requireAuth(scopeSession, cfg.rateLimit(writeBudget, cfg.revokeSessionHandler)))
+	mux.HandleFunc("POST /api/sessions/revoke-all", cfg.requireAuth(scopeSession, cfg.rateLimit(writeBudget, cfg.revokeAllSessionsHandler)))
+	mux.HandleFunc("POST /api/tokens", cfg.requireAuth(scopeSession, cfg.rateLimit(authBudget, cfg.createTokenHandler)))
+	mux.HandleFunc("GET /api/tokens", cfg.requireAuth(scopeSession, cfg.rateLimit(readBudget, cfg.listTokensHandler)))
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", cfg.requireAuth(scopeSession, cfg.rateLimit(writeBudget, cfg.revokeTokenHandler)))
+	mux.HandleFunc("POST /api/2fa/enroll", cfg.requireAuth(scopeSession, cfg.rateLimit(authBudget, cfg.enrollTwoFactorHandler)))
+	mux.HandleFunc("POST /api/2fa/confirm", cfg.requireAuth(scopeSession, cfg.rateLimit(authBudget, cfg.confirmTwoFactorHandler)))
+	mux.HandleFunc("POST /api/2fa/disable", cfg.requireAuth(scopeSession, cfg.rateLimit(authBudget, cfg.disableTwoFactorHandler)))
+	mux.HandleFunc("PUT /api/users", cfg.requireAuth(scopeSession, cfg.rateLimit(authBudget, cfg.updateUserHandler)))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.requireAuth(scopeChirpsWrite, cfg.rateLimit(writeBudget, cfg.deleteChirpHandler)))
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", cfg.requireAuth(scopeChirpsWrite, cfg.rateLimit(chirpBudget, cfg.updateChirpHandler)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/history", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.chirpHistoryHandler)))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.chirpThreadHandler)))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", cfg.requireAuth(scopeChirpsWrite, cfg.rateLimit(writeBudget, cfg.likeChirpHandler)))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.requireAuth(scopeChirpsWrite, cfg.rateLimit(writeBudget, cfg.unlikeChirpHandler)))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", cfg.requireAuth(scopeChirpsWrite, cfg.rateLimit(chirpBudget, cfg.rechirpHandler)))
 	mux.HandleFunc("POST /api/polka/webhooks", cfg.polkaWebhookHandler)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.requireAuth(scopeProfileWrite, cfg.rateLimit(writeBudget, cfg.followUserHandler)))
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.requireAuth(scopeProfileWrite, cfg.rateLimit(writeBudget, cfg.unfollowUserHandler)))
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.rateLimit(readBudget, cfg.listFollowersHandler))
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.rateLimit(readBudget, cfg.listFollowingHandler))
+	mux.HandleFunc("GET /api/users/{userID}/likes", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.listLikedChirpsHandler)))
+	mux.HandleFunc("GET /api/users/me/mentions", cfg.requireAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.mentionsHandler)))
+	mux.HandleFunc("PATCH /api/users/me", cfg.requireAuth(scopeProfileWrite, cfg.rateLimit(writeBudget, cfg.patchUserHandler)))
+	mux.HandleFunc("POST /api/users/verify", cfg.rateLimit(authBudget, cfg.verifyEmailHandler))
+	mux.HandleFunc("POST /api/users/verify/resend", cfg.requireAuth(scopeSession, cfg.rateLimit(authBudget, cfg.resendVerificationHandler)))
+	mux.HandleFunc("GET /api/users/{handle}", cfg.rateLimit(readBudget, cfg.getProfileHandler))
+	mux.HandleFunc("GET /api/timeline", cfg.requireAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.timelineHandler)))
+	mux.HandleFunc("GET /api/hashtags/trending", cfg.rateLimit(readBudget, cfg.trendingHashtagsHandler))
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", cfg.optionalAuth(scopeChirpsRead, cfg.rateLimit(readBudget, cfg.hashtagChirpsHandler)))
 
 	fileServer := http.FileServer(http.Dir("."))
 
@@ -92,3 +192,119 @@ func main() {
 	log.Fatal(server.ListenAndServe())
 
 }
+
+// newMailer picks the email sender from MAIL_SENDER. "smtp" relays through
+// SMTP_HOST; otherwise messages are written to MAIL_LOG_FILE, or to stdout
+// when PLATFORM is "dev". Any other setup is refused.
+func newMailer() (mail.Sender, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	if os.Getenv("MAIL_SENDER") == "smtp" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return mail.NewSMTPSender(
+			os.Getenv("SMTP_HOST"),
+			port,
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			from,
+		), nil
+	}
+	if path := os.Getenv("MAIL_LOG_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return mail.NewLogSender(f, from), nil
+	}
+	// Messages carry live verification, reset and login tokens, so they only
+	// go to stdout on a development machine.
+	if os.Getenv("PLATFORM") == "dev" {
+		return mail.NewLogSender(os.Stdout, from), nil
+	}
+	return nil, errors.New("MAIL_SENDER must be \"smtp\" unless PLATFORM is \"dev\" or MAIL_LOG_FILE is set")
+}
+
+// newLoginGuard picks where failed login attempts are counted from
+// LOGIN_THROTTLE_STORE. "memory" keeps them in this process, which is only
+// right for a single instance; anything else shares them through Postgres.
+func newLoginGuard(db *database.Queries) *lockout.Guard {
+	if os.Getenv("LOGIN_THROTTLE_STORE") == "memory" {
+		return lockout.NewGuard(lockout.NewMemoryStore(), lockout.DefaultAccountPolicy, lockout.DefaultIPPolicy)
+	}
+	store := lockout.NewPostgresStore(db)
+	go func() {
+		for range time.Tick(10 * time.Minute) {
+			if err := store.Sweep(context.Background(), time.Now().UTC()); err != nil {
+				log.Printf("sweeping login failures: %v", err)
+			}
+		}
+	}()
+	return lockout.NewGuard(store, lockout.DefaultAccountPolicy, lockout.DefaultIPPolicy)
+}
+
+// newRateLimitStore picks where rate limit buckets live from
+// RATE_LIMIT_STORE. "redis" shares them through the server at REDIS_URL, a
+// redis:// or rediss:// URL that may carry an ACL user name and password, so
+// every instance enforces one budget; anything else keeps them in memory.
+func newRateLimitStore() (ratelimit.Store, error) {
+	if os.Getenv("RATE_LIMIT_STORE") != "redis" {
+		return ratelimit.NewMemoryStore(), nil
+	}
+	opts, err := redis.ParseURL(os.Getenv("REDIS_URL"))
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.NewRedisStore(redis.NewClient(opts), "chirpy:ratelimit:"), nil
+}
+
+// newKeySet loads the JWT keys. JWT_SIGNING_KEY_FILE holds the PEM private
+// key new tokens are signed with; JWT_VERIFICATION_KEY_FILES lists PEM public
+// keys, comma separated, that were rotated out but still verify. Without a
+// signing key, tokens are signed HS256 with SECRET_KEY as before. Once one is
+// set, HS256 tokens are refused unless JWT_ACCEPT_HS256 is "true", which is
+// meant only for the hour after the switch while old tokens run out.
+func newKeySet() (*auth.KeySet, error) {
+	keys := auth.NewKeySet(os.Getenv("SECRET_KEY"))
+	for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := auth.ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, err := keys.AddVerificationKey(pub); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := auth.ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		kid, err := keys.AddSigningKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		log.Printf("Signing JWTs with key %s", kid)
+	}
+	if os.Getenv("JWT_ACCEPT_HS256") == "true" {
+		keys.AcceptHS256()
+		log.Println("Still accepting HS256 JWTs signed with SECRET_KEY")
+	}
+	return keys, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/lockout"
+	"github.com/akigithub888/chirpy/internal/ratelimit"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const testSecret = "test-secret"
+
+// newTestConfig returns an apiConfig whose database is a mock that expects
+// queries by their sqlc name, with rate limits and login throttling kept in
+// memory. Every expectation must be met by the end of the test.
+func newTestConfig(t *testing.T) (*apiConfig, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(queryNameMatcher))
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet database expectations: %v", err)
+		}
+		db.Close()
+	})
+	cfg := &apiConfig{
+		db:          database.New(db),
+		dbConn:      db,
+		platform:    "dev",
+		keys:        auth.NewKeySet(testSecret),
+		appURL:      "http://localhost:8080/app",
+		loginGuard:  lockout.NewGuard(lockout.NewMemoryStore(), lockout.DefaultAccountPolicy, lockout.DefaultIPPolicy),
+		rateLimiter: ratelimit.NewMemoryStore(),
+	}
+	return cfg, mock
+}
+
+// queryNameMatcher matches a query by the name sqlc gave it in its leading
+// "-- name:" comment, so expectations read like the generated methods.
+var queryNameMatcher = sqlmock.QueryMatcherFunc(func(expectedName, actualSQL string) error {
+	name, _, _ := strings.Cut(strings.TrimPrefix(actualSQL, "-- name: "), " ")
+	if name != expectedName {
+		return fmt.Errorf("got query %s, want %s", name, expectedName)
+	}
+	return nil
+})
+
+// rowsOf returns a result set holding one row per struct, with a column for
+// each field in the order the generated code scans them.
+func rowsOf(structs ...any) *sqlmock.Rows {
+	typ := reflect.TypeOf(structs[0])
+	columns := make([]string, typ.NumField())
+	for i := range columns {
+		columns[i] = typ.Field(i).Name
+	}
+	rows := sqlmock.NewRows(columns)
+	for _, s := range structs {
+		v := reflect.ValueOf(s)
+		values := make([]driver.Value, v.NumField())
+		for i := range values {
+			values[i] = driverValue(v.Field(i))
+		}
+		rows.AddRow(values...)
+	}
+	return rows
+}
+
+func driverValue(v reflect.Value) driver.Value {
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			panic(err)
+		}
+		return value
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			value, _ := pq.StringArray(v.Interface().([]string)).Value()
+			return value
+		}
+	case reflect.String:
+		return v.String()
+	}
+	return v.Interface()
+}
+
+// captureArg matches any query argument and keeps it for the test to check.
+type captureArg struct {
+	value driver.Value
+}
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+// serve runs a request through h and returns the recorded response. A
+// non-empty token is sent as a bearer token.
+func serve(h http.HandlerFunc, method, target, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("decoding response body failed: %v", err)
+	}
+}
+
+const testPassword = "correct horse battery staple"
+
+// testPasswordHash is computed once, since Argon2 is slow on purpose.
+var testPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// newTestUser returns a user whose password is testPassword.
+func newTestUser() database.User {
+	now := time.Now().UTC()
+	return database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          "user@example.com",
+		HashedPassword: testPasswordHash(),
+		DisplayName:    "User",
+		Role:           string(roleUser),
+	}
+}
+
+// noContent stands in for the handler behind a middleware.
+func noContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// route serves h under pattern, so requests get their path values.
+func route(pattern string, h http.HandlerFunc) http.HandlerFunc {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, h)
+	return mux.ServeHTTP
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/entities"
+	"github.com/google/uuid"
+)
+
+// chirpMention is a resolved @handle in a chirp body. Start and End are
+// character offsets into Body, with End exclusive.
+type chirpMention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+}
+
+// syncMentions replaces the stored mentions of a chirp with the @handles in
+// body that belong to a user. Unknown handles are left as plain text.
+func syncMentions(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	if err := q.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return err
+	}
+	mentions := entities.ExtractMentions(body)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	handles := make([]string, 0, len(mentions))
+	for _, m := range mentions {
+		handles = append(handles, entities.NormalizeHandle(m.Handle))
+	}
+	users, err := q.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[entities.NormalizeHandle(user.Handle.String)] = user.ID
+	}
+
+	var params database.CreateChirpMentionsParams
+	params.ChirpID = chirpID
+	for _, m := range mentions {
+		userID, ok := userIDs[entities.NormalizeHandle(m.Handle)]
+		if !ok {
+			continue
+		}
+		params.UserIds = append(params.UserIds, userID)
+		params.StartOffsets = append(params.StartOffsets, int32(m.Start))
+		params.EndOffsets = append(params.EndOffsets, int32(m.End))
+	}
+	if len(params.UserIds) == 0 {
+		return nil
+	}
+	return q.CreateChirpMentions(ctx, params)
+}
+
+// fillMentions attaches resolved mentions to every chirp with one query.
+func (cfg *apiConfig) fillMentions(ctx context.Context, chirps []*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	byID := make(map[uuid.UUID][]*Chirp, len(chirps))
+	for _, chirp := range chirps {
+		chirp.Mentions = []chirpMention{}
+		ids = append(ids, chirp.ID)
+		byID[chirp.ID] = append(byID[chirp.ID], chirp)
+	}
+	rows, err := cfg.db.GetChirpMentions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for _, chirp := range byID[row.ChirpID] {
+			body := []rune(chirp.Body)
+			start, end := int(row.StartOffset), int(row.EndOffset)
+			if end > len(body) || start+1 > end {
+				continue
+			}
+			chirp.Mentions = append(chirp.Mentions, chirpMention{
+				UserID: row.UserID,
+				Handle: string(body[start+1 : end]),
+				Start:  start,
+				End:    end,
+			})
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) mentionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	query := r.URL.Query()
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	scope := newCursorScope(true, "mentions", userID.String())
+	after := firstPageCursor(true)
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		after, err = decodeCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	dbChirps, err := cfg.db.ListMentionsOfUser(r.Context(), database.ListMentionsOfUserParams{
+		UserID:         userID,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting mentions")
+		return
+	}
+
+	page := chirpsPage{Chirps: make([]Chirp, 0, len(dbChirps))}
+	if len(dbChirps) > int(limit) {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID}, scope)
+	}
+	for _, dbChirp := range dbChirps {
+		page.Chirps = append(page.Chirps, chirpFromRow(database.GetChirpRow(dbChirp)))
+	}
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirpPtrs(page.Chirps)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
@@ -1,6 +1,17 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,3 +19,251 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// Scopes a personal access token can be granted. Login sessions hold all of
+// them.
+const (
+	scopeChirpsRead   = "chirps:read"
+	scopeChirpsWrite  = "chirps:write"
+	scopeProfileWrite = "profile:write"
+)
+
+var allScopes = []string{scopeChirpsRead, scopeChirpsWrite, scopeProfileWrite}
+
+// scopeSession guards account security endpoints (sessions, 2FA, tokens,
+// credentials) that only a logged-in user may reach, never a personal access
+// token.
+const scopeSession = ""
+
+type tokenType string
+
+const (
+	tokenTypeSession             tokenType = "session"
+	tokenTypePersonalAccessToken tokenType = "personal_access_token"
+)
+
+// authInfo describes the credential a request was authenticated with.
+type authInfo struct {
+	UserID    uuid.UUID
+	TokenType tokenType
+	Scopes    []string
+	// IsChirpyRed is read along with the credential, so handlers such as the
+	// rate limiter need not look the user up again.
+	IsChirpyRed bool
+}
+
+func (a authInfo) hasScope(scope string) bool {
+	if scope == scopeSession {
+		return a.TokenType == tokenTypeSession
+	}
+	return slices.Contains(a.Scopes, scope)
+}
+
+type authContextKey struct{}
+
+// authFromContext returns the credential requireAuth or optionalAuth
+// attached to the request. On an anonymous request it is the zero authInfo.
+func authFromContext(ctx context.Context) authInfo {
+	info, _ := ctx.Value(authContextKey{}).(authInfo)
+	return info
+}
+
+// viewer returns the caller's user ID for queries that personalise results,
+// such as whether the caller liked a chirp.
+func (a authInfo) viewer() uuid.NullUUID {
+	if a.TokenType == "" {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: a.UserID, Valid: true}
+}
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errInvalidToken = errors.New("invalid bearer token")
+)
+
+// authenticate resolves the bearer token to a user. Personal access tokens
+// are recognised by their prefix; anything else must be an access JWT. It
+// returns errMissingToken or errInvalidToken for bad credentials, and any
+// other error only when the lookup itself failed.
+func (cfg *apiConfig) authenticate(r *http.Request) (authInfo, error) {
+	if r.Header.Get("Authorization") == "" {
+		return authInfo{}, errMissingToken
+	}
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return authInfo{}, errInvalidToken
+	}
+
+	if !strings.HasPrefix(tokenString, personalAccessTokenPrefix) {
+		userID, err := cfg.keys.ValidateJWT(tokenString)
+		if err != nil {
+			return authInfo{}, errInvalidToken
+		}
+		// Access tokens outlive a suspension or a deleted account by up to
+		// their TTL, so check that the user may still act.
+		status, err := cfg.db.GetUserAuthStatus(r.Context(), userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return authInfo{}, errInvalidToken
+			}
+			return authInfo{}, err
+		}
+		if status.SuspendedAt.Valid {
+			return authInfo{}, errInvalidToken
+		}
+		return authInfo{UserID: userID, TokenType: tokenTypeSession, Scopes: allScopes, IsChirpyRed: status.IsChirpyRed}, nil
+	}
+
+	pat, err := cfg.db.GetPersonalAccessTokenByHash(r.Context(), auth.HashToken(tokenString))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return authInfo{}, errInvalidToken
+		}
+		return authInfo{}, err
+	}
+	if pat.RevokedAt.Valid || pat.SuspendedAt.Valid {
+		return authInfo{}, errInvalidToken
+	}
+	if pat.ExpiresAt.Valid && time.Now().UTC().After(pat.ExpiresAt.Time) {
+		return authInfo{}, errInvalidToken
+	}
+	if err := cfg.db.TouchPersonalAccessToken(r.Context(), pat.ID); err != nil {
+		return authInfo{}, err
+	}
+	return authInfo{UserID: pat.UserID, TokenType: tokenTypePersonalAccessToken, Scopes: pat.Scopes, IsChirpyRed: pat.IsChirpyRed}, nil
+}
+
+// challenge sets an RFC 6750 WWW-Authenticate header and writes the matching
+// error response. A missing token gets a bare challenge; a rejected token or
+// a missing scope names the problem so clients know whether to refresh,
+// re-authenticate or ask for a broader token.
+func challenge(w http.ResponseWriter, err error, scope string) {
+	const realm = `Bearer realm="chirpy"`
+	switch {
+	case errors.Is(err, errMissingToken):
+		w.Header().Set("WWW-Authenticate", realm)
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	case errors.Is(err, errInvalidToken):
+		w.Header().Set("WWW-Authenticate", realm+`, error="invalid_token"`)
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+	case scope == scopeSession:
+		w.Header().Set("WWW-Authenticate", realm+`, error="insufficient_scope", error_description="login session required"`)
+		respondWithError(w, http.StatusForbidden, "This endpoint requires a login session")
+	default:
+		w.Header().Set("WWW-Authenticate", realm+`, error="insufficient_scope", scope="`+scope+`"`)
+		respondWithError(w, http.StatusForbidden, "Token lacks the "+scope+" scope")
+	}
+}
+
+// requireAuth rejects requests without a valid bearer token holding scope,
+// and otherwise passes the credential to next through the request context.
+func (cfg *apiConfig) requireAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" && !cfg.limitIP(w, r, tokenBudget) {
+			return
+		}
+		info, err := cfg.authenticate(r)
+		if err != nil {
+			if errors.Is(err, errMissingToken) || errors.Is(err, errInvalidToken) {
+				challenge(w, err, scope)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error authenticating request")
+			return
+		}
+		if !info.hasScope(scope) {
+			challenge(w, nil, scope)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, info)))
+	}
+}
+
+// optionalAuth serves public endpoints that personalise their response for a
+// signed-in caller. Without a token the request proceeds anonymously, as it
+// does with a valid token that lacks scope, since the endpoint needs none.
+// A token that fails to authenticate is still rejected, so a client whose
+// token has expired learns to refresh it instead of quietly getting the
+// anonymous view.
+func (cfg *apiConfig) optionalAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" && !cfg.limitIP(w, r, tokenBudget) {
+			return
+		}
+		info, err := cfg.authenticate(r)
+		if err != nil {
+			if errors.Is(err, errMissingToken) {
+				next(w, r)
+				return
+			}
+			if errors.Is(err, errInvalidToken) {
+				challenge(w, err, scope)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error authenticating request")
+			return
+		}
+		if !info.hasScope(scope) {
+			next(w, r)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, info)))
+	}
+}
+
+type role string
+
+const (
+	roleUser      role = "user"
+	roleModerator role = "moderator"
+	roleAdmin     role = "admin"
+)
+
+var roleRanks = map[role]int{roleUser: 0, roleModerator: 1, roleAdmin: 2}
+
+func (r role) valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// atLeast reports whether r carries every permission of min. Each role
+// includes the ones below it.
+func (r role) atLeast(min role) bool {
+	return roleRanks[r] >= roleRanks[min]
+}
+
+// effectiveRole returns the role the user currently acts with. A suspended
+// account keeps its stored role but exercises none of its privileges.
+func (cfg *apiConfig) effectiveRole(ctx context.Context, userID uuid.UUID) (role, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.SuspendedAt.Valid {
+		return roleUser, nil
+	}
+	return role(user.Role), nil
+}
+
+// requireRole restricts next to logged-in users holding at least min. Roles
+// live in the database rather than the token, so a demotion takes effect on
+// the next request.
+func (cfg *apiConfig) requireRole(min role, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.requireAuth(scopeSession, func(w http.ResponseWriter, r *http.Request) {
+		current, err := cfg.effectiveRole(r.Context(), authFromContext(r.Context()).UserID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				challenge(w, errInvalidToken, scopeSession)
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+		if !current.atLeast(min) {
+			respondWithError(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	userID := uuid.New()
+	const pat = personalAccessTokenPrefix + "secret"
+
+	tests := []struct {
+		name     string
+		optional bool
+		// header is the Authorization header; token builds a bearer token
+		// from the server's keys instead.
+		header     string
+		token      func(cfg *apiConfig) string
+		expect     func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantHeader string
+		wantCaller uuid.UUID
+	}{
+		{
+			name:       "required without a token",
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `Bearer realm="chirpy"`,
+		},
+		{
+			name:       "required with a malformed header",
+			header:     "Basic dXNlcjpwYXNz",
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name:       "required with a bad token",
+			header:     "Bearer not-a-jwt",
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name: "required with a suspended user",
+			token: func(cfg *apiConfig) string {
+				token, _ := cfg.keys.MakeJWT(userID, time.Hour)
+				return token
+			},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("GetUserAuthStatus").WithArgs(userID).
+					WillReturnRows(rowsOf(database.GetUserAuthStatusRow{SuspendedAt: sql.NullTime{Time: time.Now(), Valid: true}}))
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name: "required with a valid token",
+			token: func(cfg *apiConfig) string {
+				token, _ := cfg.keys.MakeJWT(userID, time.Hour)
+				return token
+			},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("GetUserAuthStatus").WithArgs(userID).WillReturnRows(rowsOf(database.GetUserAuthStatusRow{}))
+			},
+			wantStatus: http.StatusNoContent,
+			wantCaller: userID,
+		},
+		{
+			name:       "optional without a token",
+			optional:   true,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "optional with a bad token",
+			optional:   true,
+			header:     "Bearer not-a-jwt",
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name:     "optional with an expired token",
+			optional: true,
+			token: func(cfg *apiConfig) string {
+				token, _ := cfg.keys.MakeJWT(userID, -time.Minute)
+				return token
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `Bearer realm="chirpy", error="invalid_token"`,
+		},
+		{
+			name:     "optional with a valid token",
+			optional: true,
+			token: func(cfg *apiConfig) string {
+				token, _ := cfg.keys.MakeJWT(userID, time.Hour)
+				return token
+			},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("GetUserAuthStatus").WithArgs(userID).WillReturnRows(rowsOf(database.GetUserAuthStatusRow{}))
+			},
+			wantStatus: http.StatusNoContent,
+			wantCaller: userID,
+		},
+		{
+			name:     "optional with a token lacking scope stays anonymous",
+			optional: true,
+			header:   "Bearer " + pat,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("GetPersonalAccessTokenByHash").WithArgs(auth.HashToken(pat)).
+					WillReturnRows(rowsOf(database.GetPersonalAccessTokenByHashRow{
+						ID:     uuid.New(),
+						UserID: userID,
+						Scopes: []string{scopeProfileWrite},
+					}))
+				mock.ExpectExec("TouchPersonalAccessToken").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+			var caller authInfo
+			next := func(w http.ResponseWriter, r *http.Request) {
+				caller = authFromContext(r.Context())
+				w.WriteHeader(http.StatusNoContent)
+			}
+			h := cfg.requireAuth(scopeChirpsRead, next)
+			if tt.optional {
+				h = cfg.optionalAuth(scopeChirpsRead, next)
+			}
+
+			r := httptest.NewRequest("GET", "/api/chirps", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.token != nil {
+				r.Header.Set("Authorization", "Bearer "+tt.token(cfg))
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantHeader {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantHeader)
+			}
+			if caller.UserID != tt.wantCaller {
+				t.Errorf("caller = %v, want %v", caller.UserID, tt.wantCaller)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pageCursor marks the last row of a page in (created_at, id) order.
+// Clients receive it as an opaque string and hand it back unchanged.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// firstPageCursor returns a cursor positioned before every row for the
+// given sort direction, so the first page can use the same keyset query.
+func firstPageCursor(desc bool) pageCursor {
+	if desc {
+		return pageCursor{CreatedAt: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC), ID: uuid.Max}
+	}
+	return pageCursor{CreatedAt: time.Time{}, ID: uuid.Nil}
+}
+
+// cursorScope ties a cursor to the listing that issued it: the endpoint, its
+// filters and its sort direction. A cursor replayed against other filters or
+// the other direction would silently skip or repeat rows, so decoding
+// refuses it instead.
+type cursorScope string
+
+// newCursorScope names a listing. Filters that are not set should be passed
+// as empty strings so every filter keeps its position.
+func newCursorScope(desc bool, listing string, filters ...string) cursorScope {
+	h := sha256.New()
+	h.Write([]byte(listing))
+	for _, f := range filters {
+		h.Write([]byte{0})
+		h.Write([]byte(f))
+	}
+	dir := "asc"
+	if desc {
+		dir = "desc"
+	}
+	return cursorScope(dir + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12]))
+}
+
+// uuidFilter renders an optional ID filter for newCursorScope.
+func uuidFilter(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
+
+// timeFilter renders an optional timestamp filter for newCursorScope.
+func timeFilter(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339Nano)
+}
+
+func encodeCursor(c pageCursor, scope cursorScope) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID.String() + "," + string(scope)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string, scope cursorScope) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	parts := strings.Split(string(raw), ",")
+	if len(parts) != 3 || parts[2] != string(scope) {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	return pageCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// encodeOffsetCursor and decodeOffsetCursor are used by result sets that are
+// ordered by a computed score, where keyset pagination does not apply.
+func encodeOffsetCursor(offset int32, scope cursorScope) string {
+	raw := "offset:" + strconv.Itoa(int(offset)) + "," + string(scope)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeOffsetCursor(s string, scope cursorScope) (int32, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	rest, ok := strings.CutPrefix(string(raw), "offset:")
+	if !ok {
+		return 0, errors.New("invalid cursor")
+	}
+	offsetStr, scopeStr, ok := strings.Cut(rest, ",")
+	if !ok || scopeStr != string(scope) {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.ParseInt(offsetStr, 10, 32)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return int32(offset), nil
+}
+
+// setNextPageLink points the client at the next page of a listing that
+// returns a bare array: the same request with the cursor swapped in, sent as
+// an RFC 8288 Link header.
+func setNextPageLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", cursor)
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+}
+
+// parsePageLimit reads the "limit" query parameter, falling back to
+// defaultPageLimit when it is absent.
+func parsePageLimit(s string) (int32, error) {
+	if s == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, errors.New("invalid limit")
+	}
+	return int32(limit), nil
+}
+
+// parseTimeParam parses an optional RFC 3339 timestamp query parameter.
+func parseTimeParam(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
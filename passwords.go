@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const passwordResetTokenTTL = 30 * time.Minute
+
+// forgotPasswordHandler always answers 202 so callers cannot probe which
+// addresses have accounts. The token is issued and mailed in the background
+// for the same reason: the response time should not depend on a match.
+func (cfg *apiConfig) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	type forgotPasswordRequest struct {
+		Email string `json:"email"`
+	}
+	var req forgotPasswordRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := cfg.db.GetUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("looking up user for password reset: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	go cfg.sendPasswordReset(user.ID, user.Email)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendPasswordReset replaces any outstanding reset tokens for the user and
+// mails the new one. It runs detached from the request, so failures are only
+// logged.
+func (cfg *apiConfig) sendPasswordReset(userID uuid.UUID, email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := cfg.db.DeletePasswordResetTokens(ctx, userID); err != nil {
+		log.Printf("clearing password reset tokens for user %s: %v", userID, err)
+		return
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("creating password reset token: %v", err)
+		return
+	}
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetTokenTTL),
+	})
+	if err != nil {
+		log.Printf("saving password reset token for user %s: %v", userID, err)
+		return
+	}
+
+	err = cfg.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for this account. To choose a new one, send the token below with your new password to POST /api/password/reset.\n\n%s\n\nThe token expires in %d minutes. If you did not ask for this, you can ignore this email.\n",
+			token, int(passwordResetTokenTTL.Minutes()),
+		),
+	})
+	if err != nil {
+		log.Printf("sending password reset email to user %s: %v", userID, err)
+	}
+}
+
+// resetPasswordHandler consumes a reset token, sets the new password and
+// signs the user out everywhere by revoking their refresh tokens and personal
+// access tokens, since whoever knew the old password could have made either.
+func (cfg *apiConfig) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	type resetPasswordRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	var req resetPasswordRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Token and password required")
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error hashing password")
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	record, err := qtx.ConsumePasswordResetToken(r.Context(), auth.HashToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	if time.Now().UTC().After(record.ExpiresAt) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             record.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	if err := qtx.DeletePasswordResetTokens(r.Context(), record.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+	if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), record.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking sessions")
+		return
+	}
+	if err := qtx.RevokeAllPersonalAccessTokensForUser(r.Context(), record.UserID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking access tokens")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error resetting password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/akigithub888/chirpy/internal/entities"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// Profile is the public view of a user. It never includes the email.
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	CreatedAt      time.Time `json:"created_at"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	ChirpCount     int64     `json:"chirp_count"`
+}
+
+// validateProfile checks user-editable profile fields and returns a message
+// suitable for a 400 response, or "" when they are acceptable. An empty
+// handle means the user has none.
+func validateProfile(handle, displayName, bio string) string {
+	if handle != "" && !entities.ValidHandle(handle) {
+		return "Invalid handle"
+	}
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return "Display name is too long"
+	}
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return "Bio is too long"
+	}
+	return ""
+}
+
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	handle := entities.NormalizeHandle(r.PathValue("handle"))
+	if !entities.ValidHandle(handle) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	row, err := cfg.db.GetUserProfileByHandle(r.Context(), handle)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, Profile{
+		ID:             row.ID,
+		Handle:         row.Handle.String,
+		DisplayName:    row.DisplayName,
+		Bio:            row.Bio,
+		CreatedAt:      row.CreatedAt,
+		IsChirpyRed:    row.IsChirpyRed,
+		FollowerCount:  row.FollowerCount,
+		FollowingCount: row.FollowingCount,
+		ChirpCount:     row.ChirpCount,
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/ratelimit"
+)
+
+// rateBudget is one class of requests that share a bucket per caller.
+// Chirpy Red members get the larger red limit.
+type rateBudget struct {
+	name     string
+	limit    ratelimit.Limit
+	redLimit ratelimit.Limit
+}
+
+var (
+	readBudget = rateBudget{
+		name:     "read",
+		limit:    ratelimit.Limit{Burst: 120, Period: time.Minute},
+		redLimit: ratelimit.Limit{Burst: 600, Period: time.Minute},
+	}
+	chirpBudget = rateBudget{
+		name:     "chirp",
+		limit:    ratelimit.Limit{Burst: 20, Period: 10 * time.Minute},
+		redLimit: ratelimit.Limit{Burst: 100, Period: 10 * time.Minute},
+	}
+	// Everything else that changes state: likes, follows, edits and
+	// revocations.
+	writeBudget = rateBudget{
+		name:     "write",
+		limit:    ratelimit.Limit{Burst: 60, Period: time.Minute},
+		redLimit: ratelimit.Limit{Burst: 300, Period: time.Minute},
+	}
+	// Auth endpoints are mostly called before the caller has a token, so
+	// this budget is almost always counted per IP.
+	authBudget = rateBudget{
+		name:     "auth",
+		limit:    ratelimit.Limit{Burst: 10, Period: time.Minute},
+		redLimit: ratelimit.Limit{Burst: 10, Period: time.Minute},
+	}
+	// Every request presenting a bearer token is counted against its IP
+	// before the token is checked, so a flood of made-up tokens is cut off
+	// before each one costs a database lookup. The caller is not known yet,
+	// so there is no red limit.
+	tokenBudget = rateBudget{
+		name:     "token",
+		limit:    ratelimit.Limit{Burst: 600, Period: time.Minute},
+		redLimit: ratelimit.Limit{Burst: 600, Period: time.Minute},
+	}
+)
+
+// rateLimit spends a token from the caller's bucket in budget before calling
+// next. Authenticated callers are counted per user, so it must run inside
+// requireAuth or optionalAuth to see them; everyone else is counted per IP.
+func (cfg *apiConfig) rateLimit(budget rateBudget, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := budget.limit
+		var key string
+		if caller := authFromContext(r.Context()); caller.TokenType != "" {
+			key = budget.name + ":user:" + caller.UserID.String()
+			if caller.IsChirpyRed {
+				limit = budget.redLimit
+			}
+		} else {
+			_, ip := clientMetadata(r)
+			key = budget.name + ":ip:" + ip
+		}
+		if cfg.spend(w, r, key, limit) {
+			next(w, r)
+		}
+	}
+}
+
+// limitIP spends a token from the client IP's bucket in budget, whoever the
+// caller turns out to be. It reports whether the request may go on, having
+// already answered it with 429 if not.
+func (cfg *apiConfig) limitIP(w http.ResponseWriter, r *http.Request, budget rateBudget) bool {
+	_, ip := clientMetadata(r)
+	return cfg.spend(w, r, budget.name+":ip:"+ip, budget.limit)
+}
+
+// spend takes a token from the bucket at key, sets the RateLimit headers and
+// answers with 429 when the bucket is empty. If the store fails the request
+// is let through, since an outage of the limiter should not take the API
+// down with it.
+func (cfg *apiConfig) spend(w http.ResponseWriter, r *http.Request, key string, limit ratelimit.Limit) bool {
+	res, err := cfg.rateLimiter.Take(r.Context(), key, limit, time.Now().UTC())
+	if err != nil {
+		log.Printf("rate limiter unavailable: %v", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+strconv.Itoa(int(limit.Period.Seconds())))
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		respondWithError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
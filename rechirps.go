@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func newChirpReference(id uuid.NullUUID) *chirpReference {
+	if !id.Valid {
+		return nil
+	}
+	return &chirpReference{ID: id.UUID}
+}
+
+// fillReferences loads the originals behind every quote and rechirp in
+// chirps and returns them. Only one level is resolved: an embedded original's
+// own references keep just their IDs.
+func (cfg *apiConfig) fillReferences(ctx context.Context, chirps []*Chirp) ([]*Chirp, error) {
+	var refs []*chirpReference
+	var ids []uuid.UUID
+	for _, chirp := range chirps {
+		for _, ref := range []*chirpReference{chirp.QuoteOf, chirp.RechirpOf} {
+			if ref != nil {
+				refs = append(refs, ref)
+				ids = append(ids, ref.ID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := cfg.db.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*Chirp, len(rows))
+	originals := make([]*Chirp, 0, len(rows))
+	for _, row := range rows {
+		original := chirpFromRow(database.GetChirpRow(row))
+		byID[row.ID] = &original
+		originals = append(originals, &original)
+	}
+	for _, ref := range refs {
+		ref.Chirp = byID[ref.ID]
+		ref.Deleted = ref.Chirp == nil
+	}
+	return originals, nil
+}
+
+// resolveOriginal returns the chirp a new quote or rechirp should point at.
+// Rechirps carry no content of their own, so references to them are
+// redirected to the chirp they reshare.
+func (cfg *apiConfig) resolveOriginal(ctx context.Context, chirpID uuid.UUID) (uuid.UUID, error) {
+	dbChirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if dbChirp.RechirpOf.Valid {
+		return dbChirp.RechirpOf.UUID, nil
+	}
+	return dbChirp.ID, nil
+}
+
+func (cfg *apiConfig) rechirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+	if !cfg.requireVerifiedEmail(w, r, userID) {
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+	originalID, err := cfg.resolveOriginal(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+		return
+	}
+
+	dbChirp, err := cfg.db.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:    userID,
+		RechirpOf: uuid.NullUUID{UUID: originalID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusConflict, "Chirp already rechirped")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error creating rechirp")
+		return
+	}
+
+	chirp := chirpFromRow(database.GetChirpRow(dbChirp))
+	if err := cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, []*Chirp{&chirp}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, chirp)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type searchResult struct {
+	Chirp
+	Rank float32 `json:"rank"`
+}
+
+type searchPage struct {
+	Results    []searchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing search query")
+		return
+	}
+
+	limit, err := parsePageLimit(query.Get("limit"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	var authorID uuid.NullUUID
+	if authorIDStr := query.Get("author_id"); authorIDStr != "" {
+		authorID.UUID, err = uuid.Parse(authorIDStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+			return
+		}
+		authorID.Valid = true
+	}
+
+	scope := newCursorScope(true, "search", q, uuidFilter(authorID))
+	var offset int32
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		offset, err = decodeOffsetCursor(cursorStr, scope)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	rows, err := cfg.db.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:      q,
+		UserID:     authorID,
+		PageLimit:  limit + 1,
+		PageOffset: offset,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error searching chirps")
+		return
+	}
+
+	page := searchPage{Results: make([]searchResult, 0, len(rows))}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		page.NextCursor = encodeOffsetCursor(offset+limit, scope)
+	}
+	for _, row := range rows {
+		page.Results = append(page.Results, searchResult{
+			Chirp: Chirp{
+				ID:        row.ID,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				Body:      row.Body,
+				UserID:    row.UserID,
+				InReplyTo: row.InReplyTo,
+				QuoteOf:   newChirpReference(row.QuoteOf),
+				RechirpOf: newChirpReference(row.RechirpOf),
+			},
+			Rank: row.Rank,
+		})
+	}
+	chirps := make([]*Chirp, 0, len(page.Results))
+	for i := range page.Results {
+		chirps = append(chirps, &page.Results[i].Chirp)
+	}
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, page)
+}
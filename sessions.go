@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxUserAgentLength = 512
+
+// Session is one signed-in device: a refresh token family, identified by its
+// family ID so the ID survives rotation.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+// clientMetadata returns the user agent and IP address recorded against a
+// refresh token. The IP is the connection's peer address; forwarding headers
+// are not trusted.
+func clientMetadata(r *http.Request) (userAgent, ip string) {
+	// Postgres rejects invalid UTF-8 in TEXT columns, so replace any bytes
+	// the client sent that are not UTF-8 and cut on a rune boundary.
+	userAgent = strings.ToValidUTF8(r.UserAgent(), "\uFFFD")
+	if len(userAgent) > maxUserAgentLength {
+		cut := maxUserAgentLength
+		for cut > 0 && !utf8.RuneStart(userAgent[cut]) {
+			cut--
+		}
+		userAgent = userAgent[:cut]
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return userAgent, ip
+}
+
+func (cfg *apiConfig) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	rows, err := cfg.db.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting sessions")
+		return
+	}
+
+	now := time.Now().UTC()
+	resp := struct {
+		Sessions []Session `json:"sessions"`
+	}{Sessions: make([]Session, 0, len(rows))}
+	for _, row := range rows {
+		if now.After(row.ExpiresAt) {
+			continue
+		}
+		resp.Sessions = append(resp.Sessions, Session{
+			ID:         row.FamilyID,
+			CreatedAt:  row.SessionCreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	revoked, err := cfg.db.RevokeRefreshTokenFamily(r.Context(), database.RevokeRefreshTokenFamilyParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeAllSessionsHandler signs the user out everywhere. Personal access
+// tokens go too: a user who suspects their account was taken over should not
+// have to find and revoke the tokens an intruder may have made one by one.
+func (cfg *apiConfig) revokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking sessions")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking sessions")
+		return
+	}
+	if err := qtx.RevokeAllPersonalAccessTokensForUser(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking access tokens")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,40 @@
+-- name: CreateChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT
+    sqlc.arg('chirp_id')::uuid,
+    UNNEST(sqlc.arg('tags')::text[]),
+    -- Tags date from the chirp, so re-syncing them after an edit does not
+    -- make an old chirp count towards trending again.
+    (SELECT created_at FROM chirps WHERE id = sqlc.arg('chirp_id'))
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: ListChirpsByHashtag :many
+SELECT
+    c.id,
+    c.created_at,
+    c.updated_at,
+    c.body,
+    c.user_id,
+    c.in_reply_to,
+    c.quote_of,
+    c.rechirp_of
+FROM chirp_hashtags h
+JOIN chirps c ON c.id = h.chirp_id
+WHERE h.tag = sqlc.arg('tag')
+  AND (c.created_at, c.id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListTrendingHashtags :many
+SELECT
+    tag,
+    COUNT(*) AS uses
+FROM chirp_hashtags
+WHERE created_at >= NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second'
+GROUP BY tag
+ORDER BY uses DESC, tag ASC
+LIMIT sqlc.arg('page_limit');
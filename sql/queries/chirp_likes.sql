@@ -0,0 +1,36 @@
+-- name: CreateChirpLike :exec
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: DeleteChirpLike :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1
+  AND chirp_id = $2;
+
+-- name: GetChirpLikeStats :many
+SELECT
+    chirp_id,
+    COUNT(*) AS like_count,
+    COALESCE(BOOL_OR(user_id = sqlc.narg('viewer_id')::uuid), FALSE)::bool AS liked_by_me
+FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
+
+-- name: ListLikedChirps :many
+SELECT
+    c.id,
+    c.created_at,
+    c.updated_at,
+    c.body,
+    c.user_id,
+    c.in_reply_to,
+    c.quote_of,
+    c.rechirp_of,
+    l.created_at AS liked_at
+FROM chirp_likes l
+JOIN chirps c ON c.id = l.chirp_id
+WHERE l.user_id = sqlc.arg('user_id')
+  AND (l.created_at, l.chirp_id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY l.created_at DESC, l.chirp_id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,42 @@
+-- name: CreateChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset)
+SELECT
+    sqlc.arg('chirp_id')::uuid,
+    UNNEST(sqlc.arg('user_ids')::uuid[]),
+    UNNEST(sqlc.arg('start_offsets')::int[]),
+    UNNEST(sqlc.arg('end_offsets')::int[]);
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpMentions :many
+SELECT
+    chirp_id,
+    user_id,
+    start_offset,
+    end_offset
+FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, start_offset;
+
+-- name: ListMentionsOfUser :many
+SELECT
+    c.id,
+    c.created_at,
+    c.updated_at,
+    c.body,
+    c.user_id,
+    c.in_reply_to,
+    c.quote_of,
+    c.rechirp_of
+FROM chirps c
+WHERE EXISTS (
+    SELECT 1
+    FROM chirp_mentions m
+    WHERE m.chirp_id = c.id
+      AND m.user_id = sqlc.arg('user_id')
+)
+  AND (c.created_at, c.id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,27 @@
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (
+    id,
+    chirp_id,
+    body,
+    created_at,
+    replaced_at
+)
+SELECT
+    gen_random_uuid(),
+    id,
+    body,
+    updated_at,
+    NOW()
+FROM chirps
+WHERE chirps.id = $1;
+
+-- name: ListChirpRevisions :many
+SELECT
+    id,
+    chirp_id,
+    body,
+    created_at,
+    replaced_at
+FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC;
@@ -4,36 +4,90 @@ INSERT INTO chirps (
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 )
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
+    $2,
+    $3,
+    $4,
+    NULL
+)
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quote_of, rechirp_of;
+
+-- name: GetChirp :one
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE id = $1;
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    rechirp_of
+)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
     $2
 )
-RETURNING id, created_at, updated_at, body, user_id;
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quote_of, rechirp_of;
 
--- name: GetChirps :many
+-- name: GetChirpsByIDs :many
 SELECT
     id,
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 FROM chirps
-ORDER BY created_at ASC;
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
 
--- name: GetChirp :one
+-- name: GetChirpForUpdate :one
 SELECT
     id,
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 FROM chirps
-WHERE id = $1;
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET
+    body = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, quote_of, rechirp_of;
 
 -- name: DeleteAllChirps :exec
 DELETE FROM chirps;
@@ -43,13 +97,173 @@ DELETE FROM chirps;
 DELETE FROM chirps
 WHERE id = $1;
 
--- name: GetChirpsByUser :many
+-- name: ListChirps :many
 SELECT
     id,
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
 FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC;
\ No newline at end of file
+WHERE (created_at, id) > (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListChirpsDesc :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE (created_at, id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListChirpsByUser :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+  AND (created_at, id) > (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListChirpsByUserDesc :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+  AND (created_at, id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: SearchChirps :many
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of,
+    ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+  AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id'))
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit')
+OFFSET sqlc.arg('page_offset');
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT
+        parent.id,
+        parent.created_at,
+        parent.updated_at,
+        parent.body,
+        parent.user_id,
+        parent.in_reply_to,
+        parent.quote_of,
+        parent.rechirp_of,
+        1 AS depth
+    FROM chirps child
+    JOIN chirps parent ON parent.id = child.in_reply_to
+    WHERE child.id = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT
+        c.id,
+        c.created_at,
+        c.updated_at,
+        c.body,
+        c.user_id,
+        c.in_reply_to,
+        c.quote_of,
+        c.rechirp_of,
+        a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+    WHERE a.depth < sqlc.arg('max_depth')::int
+)
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpReplies :many
+WITH RECURSIVE replies AS (
+    SELECT
+        c.id,
+        c.created_at,
+        c.updated_at,
+        c.body,
+        c.user_id,
+        c.in_reply_to,
+        c.quote_of,
+        c.rechirp_of,
+        1 AS depth
+    FROM chirps c
+    WHERE c.in_reply_to = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT
+        c.id,
+        c.created_at,
+        c.updated_at,
+        c.body,
+        c.user_id,
+        c.in_reply_to,
+        c.quote_of,
+        c.rechirp_of,
+        r.depth + 1
+    FROM chirps c
+    JOIN replies r ON c.in_reply_to = r.id
+    WHERE r.depth < sqlc.arg('max_depth')::int
+)
+SELECT
+    id,
+    created_at,
+    updated_at,
+    body,
+    user_id,
+    in_reply_to,
+    quote_of,
+    rechirp_of
+FROM replies
+ORDER BY depth ASC, created_at ASC, id ASC;
@@ -0,0 +1,13 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, created_at, expires_at)
+VALUES ($1, $2, $3, NOW(), $4);
+
+-- name: DeleteEmailVerificationTokens :exec
+DELETE FROM email_verification_tokens
+WHERE user_id = $1;
+
+-- name: ConsumeEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL
+RETURNING user_id, email, expires_at;
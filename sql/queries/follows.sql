@@ -0,0 +1,46 @@
+-- name: CreateFollow :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = $1
+  AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT
+    follower_id AS user_id,
+    created_at
+FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+  AND (created_at, follower_id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListFollowing :many
+SELECT
+    followee_id AS user_id,
+    created_at
+FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+  AND (created_at, followee_id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: ListTimeline :many
+SELECT
+    c.id,
+    c.created_at,
+    c.updated_at,
+    c.body,
+    c.user_id,
+    c.in_reply_to,
+    c.quote_of,
+    c.rechirp_of
+FROM chirps c
+JOIN follows f ON f.followee_id = c.user_id
+WHERE f.follower_id = sqlc.arg('follower_id')
+  AND (c.created_at, c.id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,38 @@
+-- name: GetLoginFailures :one
+SELECT failures, last_failure_at
+FROM login_failures
+WHERE key = sqlc.arg('key') AND expires_at > sqlc.arg('now');
+
+-- name: ReserveFirstLoginAttempt :execrows
+INSERT INTO login_failures (key, failures, last_failure_at, expires_at)
+VALUES (sqlc.arg('key'), 1, sqlc.arg('now'), sqlc.arg('expires_at'))
+ON CONFLICT (key) DO UPDATE
+SET
+    failures = 1,
+    last_failure_at = EXCLUDED.last_failure_at,
+    expires_at = EXCLUDED.expires_at
+WHERE login_failures.expires_at <= EXCLUDED.last_failure_at;
+
+-- name: ReserveLoginAttempt :execrows
+UPDATE login_failures
+SET
+    failures = failures + 1,
+    last_failure_at = sqlc.arg('now'),
+    expires_at = sqlc.arg('expires_at')
+WHERE key = sqlc.arg('key')
+  AND failures = sqlc.arg('prev_failures')
+  AND last_failure_at = sqlc.arg('prev_failure_at')
+  AND expires_at > sqlc.arg('now');
+
+-- name: ReleaseLoginAttempt :exec
+UPDATE login_failures
+SET failures = failures - 1
+WHERE key = $1 AND failures > 0;
+
+-- name: DeleteLoginFailures :exec
+DELETE FROM login_failures
+WHERE key = $1;
+
+-- name: DeleteExpiredLoginFailures :exec
+DELETE FROM login_failures
+WHERE expires_at <= sqlc.arg('now');
@@ -0,0 +1,13 @@
+-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3);
+
+-- name: DeleteMagicLinkTokens :exec
+DELETE FROM magic_link_tokens
+WHERE user_id = $1;
+
+-- name: ConsumeMagicLinkToken :one
+UPDATE magic_link_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL
+RETURNING user_id, expires_at;
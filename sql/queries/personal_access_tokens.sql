@@ -0,0 +1,40 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, token_hash, user_id, name, scopes, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    sqlc.arg('token_hash'),
+    sqlc.arg('user_id'),
+    sqlc.arg('name'),
+    sqlc.arg('scopes')::text[],
+    NOW(),
+    sqlc.narg('expires_at')
+)
+RETURNING id, name, scopes, created_at, expires_at, last_used_at;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT t.id, t.user_id, t.scopes, t.expires_at, t.revoked_at, u.suspended_at, u.is_chirpy_red
+FROM personal_access_tokens t
+JOIN users u ON u.id = t.user_id
+WHERE t.token_hash = $1;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: ListPersonalAccessTokens :many
+SELECT id, name, scopes, created_at, expires_at, last_used_at
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllPersonalAccessTokensForUser :exec
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
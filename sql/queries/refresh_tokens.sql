@@ -1,14 +1,17 @@
--- name: CreateRefreshToken :one
+-- name: CreateRefreshToken :exec
 INSERT INTO refresh_tokens (
-    token,
+    token_hash,
     created_at,
     updated_at,
     user_id,
     expires_at,
-    revoked_at
+    revoked_at,
+    family_id,
+    session_created_at,
+    user_agent,
+    ip_address
 )
-VALUES ($1, NOW(), NOW(), $2, $3, NULL)
-RETURNING token;
+VALUES ($1, NOW(), NOW(), $2, $3, NULL, $4, $5, $6, $7);
 
 -- name: GetUserFromRefreshToken :one
 SELECT
@@ -16,18 +19,56 @@ SELECT
     u.email,
     u.created_at,
     u.updated_at,
-    r.token,
+    r.token_hash,
     r.expires_at,
-    r.revoked_at
+    r.revoked_at,
+    r.family_id,
+    r.replaced_by,
+    r.session_created_at
 FROM refresh_tokens r
 JOIN users u ON r.user_id = u.id
-WHERE r.token = $1;
+WHERE r.token_hash = $1;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
+WHERE token_hash = $1;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET replaced_by = $2,
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
+  AND replaced_by IS NULL
+  AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1
+  AND user_id = $2
+  AND revoked_at IS NULL;
 
 -- name: DeleteAllRefreshTokens :exec
 DELETE FROM refresh_tokens;
+
+-- name: RevokeAllRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: ListActiveSessions :many
+SELECT
+    family_id,
+    session_created_at,
+    created_at AS last_used_at,
+    expires_at,
+    user_agent,
+    ip_address
+FROM refresh_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
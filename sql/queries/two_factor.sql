@@ -0,0 +1,49 @@
+-- name: UpsertPendingTOTPCredential :exec
+INSERT INTO totp_credentials (user_id, secret, created_at, enabled_at, last_used_step)
+VALUES ($1, $2, NOW(), NULL, 0)
+ON CONFLICT (user_id) DO UPDATE
+SET
+    secret = EXCLUDED.secret,
+    created_at = EXCLUDED.created_at,
+    enabled_at = NULL,
+    last_used_step = 0;
+
+-- name: GetTOTPCredential :one
+SELECT user_id, secret, created_at, enabled_at, last_used_step, challenges_revoked_at
+FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: EnableTOTPCredential :exec
+UPDATE totp_credentials
+SET enabled_at = NOW()
+WHERE user_id = $1;
+
+-- name: MarkTOTPStepUsed :execrows
+UPDATE totp_credentials
+SET last_used_step = sqlc.arg('step')
+WHERE user_id = sqlc.arg('user_id') AND last_used_step < sqlc.arg('step');
+
+-- name: RevokeTOTPChallenges :exec
+UPDATE totp_credentials
+SET challenges_revoked_at = NOW()
+WHERE user_id = $1;
+
+-- name: DeleteTOTPCredential :exec
+DELETE FROM totp_credentials
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (code_hash, user_id, created_at)
+SELECT
+    UNNEST(sqlc.arg('code_hashes')::text[]),
+    sqlc.arg('user_id')::uuid,
+    NOW();
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
@@ -1,38 +1,138 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
     $2,
-    false
+    false,
+    $3,
+    $4,
+    $5
 )
 RETURNING 
     id,
     created_at,
     updated_at,
     email,
-    is_chirpy_red;
+    is_chirpy_red,
+    handle,
+    display_name,
+    bio;
 
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
 
 -- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, role, suspended_at
 FROM users
 WHERE email = $1;
 
--- name: UpdateUser :one
+-- name: UpdateUserEmail :exec
 UPDATE users
 SET
     email = $2,
-    hashed_password = $3,
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
     updated_at = NOW()
-WHERE id = $1
-RETURNING id, email, created_at, updated_at, is_chirpy_red;
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND email = $2;
 
 -- name: UpgradeToChirpyRed :exec
 UPDATE users
 SET is_chirpy_red = TRUE
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, email_verified_at, role, suspended_at
+FROM users
+WHERE id = $1;
+
+-- name: GetUserAuthStatus :one
+SELECT suspended_at, is_chirpy_red
+FROM users
+WHERE id = $1;
+
+
+-- name: GetUsersByHandles :many
+SELECT id, handle
+FROM users
+WHERE LOWER(handle) = ANY(sqlc.arg('handles')::text[]);
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, is_chirpy_red, handle, display_name, bio, email_verified_at;
+
+-- name: GetUserProfileByHandle :one
+SELECT
+    u.id,
+    u.handle,
+    u.display_name,
+    u.bio,
+    u.created_at,
+    u.is_chirpy_red,
+    (SELECT COUNT(*) FROM follows f WHERE f.followee_id = u.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows f WHERE f.follower_id = u.id) AS following_count,
+    (SELECT COUNT(*) FROM chirps c WHERE c.user_id = u.id) AS chirp_count
+FROM users u
+WHERE LOWER(u.handle) = LOWER(sqlc.arg('handle'));
+
+-- name: ListUsers :many
+SELECT id, created_at, updated_at, email, is_chirpy_red, handle, display_name, bio, email_verified_at, role, suspended_at
+FROM users
+WHERE (
+    sqlc.arg('pattern')::text = ''
+    OR email ILIKE sqlc.arg('pattern')
+    OR handle ILIKE sqlc.arg('pattern')
+    OR display_name ILIKE sqlc.arg('pattern')
+  )
+  AND (created_at, id) < (sqlc.arg('after_created_at')::timestamp, sqlc.arg('after_id')::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: SetUserRole :execrows
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SuspendUser :execrows
+UPDATE users
+SET
+    suspended_at = COALESCE(suspended_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ReactivateUser :execrows
+UPDATE users
+SET
+    suspended_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetChirpyRed :execrows
+UPDATE users
+SET
+    is_chirpy_red = $2,
+    updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID
+    CONSTRAINT chirps_in_reply_to_fkey
+        REFERENCES chirps(id)
+        ON DELETE SET NULL;
+
+CREATE INDEX chirps_in_reply_to_idx
+    ON chirps (in_reply_to);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_in_reply_to_idx;
+
+ALTER TABLE chirps
+DROP COLUMN in_reply_to;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- quote_of and rechirp_of deliberately have no foreign key: the reference
+-- must survive the original's deletion so readers can render a tombstone.
+ALTER TABLE chirps
+ADD COLUMN quote_of UUID,
+ADD COLUMN rechirp_of UUID;
+
+CREATE INDEX chirps_quote_of_idx
+    ON chirps (quote_of);
+
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_key
+    ON chirps (user_id, rechirp_of)
+    WHERE rechirp_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_user_id_rechirp_of_key;
+DROP INDEX IF EXISTS chirps_quote_of_idx;
+
+ALTER TABLE chirps
+DROP COLUMN rechirp_of,
+DROP COLUMN quote_of;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag)
+);
+
+CREATE INDEX chirp_hashtags_tag_created_at_idx
+    ON chirp_hashtags (tag, created_at, chirp_id);
+
+CREATE INDEX chirp_hashtags_created_at_idx
+    ON chirp_hashtags (created_at);
+
+-- +goose Down
+DROP TABLE chirp_hashtags;
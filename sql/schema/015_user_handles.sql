@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT;
+
+CREATE UNIQUE INDEX users_handle_lower_key
+    ON users (LOWER(handle));
+
+-- +goose Down
+DROP INDEX IF EXISTS users_handle_lower_key;
+
+ALTER TABLE users
+DROP COLUMN handle;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Existing tokens keep working: the value clients hold hashes to the
+-- digest stored here.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens
+SET
+    token_hash = encode(digest(token_hash, 'sha256'), 'hex'),
+    replaced_by = encode(digest(replaced_by, 'sha256'), 'hex');
+
+-- +goose Down
+-- Digests cannot be turned back into tokens, so every session ends.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
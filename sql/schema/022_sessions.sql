@@ -0,0 +1,31 @@
+-- +goose Up
+-- A session is a refresh token family; session_created_at is carried over
+-- on every rotation so the session keeps its login time.
+ALTER TABLE refresh_tokens
+ADD COLUMN session_created_at TIMESTAMP,
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+
+UPDATE refresh_tokens r
+SET session_created_at = f.started_at
+FROM (
+    SELECT family_id, MIN(created_at) AS started_at
+    FROM refresh_tokens
+    GROUP BY family_id
+) f
+WHERE r.family_id = f.family_id;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN session_created_at SET NOT NULL;
+
+CREATE INDEX refresh_tokens_active_user_id_idx
+ON refresh_tokens (user_id)
+WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP INDEX refresh_tokens_active_user_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN ip_address,
+DROP COLUMN user_agent,
+DROP COLUMN session_created_at;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- enabled_at stays NULL until the user confirms enrollment with a first
+-- code. last_used_step is the TOTP time step of the last accepted code, so
+-- a code cannot be replayed within its window.
+CREATE TABLE totp_credentials (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE recovery_codes (
+    code_hash TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE totp_credentials;
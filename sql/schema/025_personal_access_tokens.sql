@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX personal_access_tokens_user_id_idx
+ON personal_access_tokens (user_id);
+
+-- +goose Down
+DROP TABLE personal_access_tokens;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin')),
+ADD COLUMN suspended_at TIMESTAMP;
+
+-- There is no way to sign up as an admin; promote the first one by hand:
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+
+CREATE INDEX users_created_at_id_idx
+ON users (created_at, id);
+
+-- +goose Down
+DROP INDEX users_created_at_id_idx;
+
+ALTER TABLE users
+DROP COLUMN suspended_at,
+DROP COLUMN role;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Failed login attempts per throttling key ("account:<email>" or
+-- "ip:<address>"). A row past expires_at is treated as absent.
+CREATE TABLE login_failures (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX login_failures_expires_at_idx
+ON login_failures (expires_at);
+
+-- +goose Down
+DROP TABLE login_failures;
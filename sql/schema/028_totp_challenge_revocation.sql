@@ -0,0 +1,9 @@
+-- +goose Up
+-- Login challenges issued at or before challenges_revoked_at are no longer
+-- accepted.
+ALTER TABLE totp_credentials
+ADD COLUMN challenges_revoked_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE totp_credentials
+DROP COLUMN challenges_revoked_at;
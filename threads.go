@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxThreadDepth bounds how far the recursive thread queries walk in
+// either direction.
+const maxThreadDepth = 50
+
+type threadNode struct {
+	Chirp
+	Replies []*threadNode `json:"replies"`
+}
+
+func (cfg *apiConfig) chirpThreadHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID")
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirp")
+		return
+	}
+
+	dbAncestors, err := cfg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ChirpID:  chirpID,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting thread")
+		return
+	}
+
+	dbReplies, err := cfg.db.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+		ChirpID:  chirpID,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting thread")
+		return
+	}
+
+	ancestors := make([]Chirp, 0, len(dbAncestors))
+	for _, dbAncestor := range dbAncestors {
+		ancestors = append(ancestors, chirpFromRow(database.GetChirpRow(dbAncestor)))
+	}
+
+	// Replies arrive ordered by depth, so every parent is indexed before
+	// its children are attached.
+	root := &threadNode{Chirp: chirpFromRow(dbChirp), Replies: []*threadNode{}}
+	nodes := map[uuid.UUID]*threadNode{root.ID: root}
+	chirps := append(chirpPtrs(ancestors), &root.Chirp)
+	for _, dbReply := range dbReplies {
+		parent, ok := nodes[dbReply.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		node := &threadNode{Chirp: chirpFromRow(database.GetChirpRow(dbReply)), Replies: []*threadNode{}}
+		parent.Replies = append(parent.Replies, node)
+		nodes[node.ID] = node
+		chirps = append(chirps, &node.Chirp)
+	}
+	if err := cfg.hydrateChirps(r.Context(), authFromContext(r.Context()).viewer(), chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting chirps")
+		return
+	}
+
+	resp := struct {
+		Ancestors []Chirp     `json:"ancestors"`
+		Chirp     *threadNode `json:"chirp"`
+	}{
+		Ancestors: ancestors,
+		Chirp:     root,
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
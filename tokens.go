@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// personalAccessTokenPrefix marks a bearer token as a personal access token
+// rather than a JWT, and makes leaked tokens easy to find in logs and code.
+const personalAccessTokenPrefix = "chirpy_pat_"
+
+const (
+	maxTokenNameLength   = 100
+	maxTokenLifetimeDays = 365
+)
+
+// PersonalAccessToken is a long-lived token a user creates for scripts and
+// integrations. The secret itself is only ever returned by the create call.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func (cfg *apiConfig) createTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	type createTokenRequest struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	var req createTokenRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxTokenNameLength {
+		respondWithError(w, http.StatusBadRequest, "Token name must be between 1 and 100 characters")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "At least one scope is required")
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(allScopes, scope) {
+			respondWithError(w, http.StatusBadRequest, "Unknown scope: "+scope)
+			return
+		}
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxTokenLifetimeDays {
+		respondWithError(w, http.StatusBadRequest, "expires_in_days must be between 0 and 365")
+		return
+	}
+
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating token")
+		return
+	}
+	token := personalAccessTokenPrefix + secret
+
+	params := database.CreatePersonalAccessTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		params.ExpiresAt.Time = time.Now().UTC().AddDate(0, 0, req.ExpiresInDays)
+		params.ExpiresAt.Valid = true
+	}
+	row, err := cfg.db.CreatePersonalAccessToken(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating token")
+		return
+	}
+
+	resp := PersonalAccessToken{
+		ID:        row.ID,
+		Name:      row.Name,
+		Scopes:    row.Scopes,
+		CreatedAt: row.CreatedAt,
+		Token:     token,
+	}
+	if row.ExpiresAt.Valid {
+		resp.ExpiresAt = &row.ExpiresAt.Time
+	}
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) listTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	rows, err := cfg.db.ListPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting tokens")
+		return
+	}
+
+	resp := struct {
+		Tokens []PersonalAccessToken `json:"tokens"`
+	}{Tokens: make([]PersonalAccessToken, 0, len(rows))}
+	for _, row := range rows {
+		token := PersonalAccessToken{
+			ID:        row.ID,
+			Name:      row.Name,
+			Scopes:    row.Scopes,
+			CreatedAt: row.CreatedAt,
+		}
+		if row.ExpiresAt.Valid {
+			token.ExpiresAt = &row.ExpiresAt.Time
+		}
+		if row.LastUsedAt.Valid {
+			token.LastUsedAt = &row.LastUsedAt.Time
+		}
+		resp.Tokens = append(resp.Tokens, token)
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) revokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	revoked, err := cfg.db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error revoking token")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
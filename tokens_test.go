@@ -0,0 +1,186 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestCreateToken(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantScopes string
+		wantStatus int
+	}{
+		{
+			name:       "scopes are sorted and deduplicated",
+			body:       `{"name":" deploy bot ","scopes":["chirps:write","chirps:read","chirps:write"]}`,
+			wantScopes: `{"chirps:read","chirps:write"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown scope",
+			body:       `{"name":"bot","scopes":["admin"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no scopes",
+			body:       `{"name":"bot","scopes":[]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "lifetime too long",
+			body:       `{"name":"bot","scopes":["chirps:read"],"expires_in_days":366}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			session, err := cfg.keys.MakeJWT(userID, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT failed: %v", err)
+			}
+			mock.ExpectQuery("GetUserAuthStatus").WithArgs(userID).WillReturnRows(rowsOf(database.GetUserAuthStatusRow{}))
+			stored := &captureArg{}
+			if tt.wantStatus == http.StatusCreated {
+				mock.ExpectQuery("CreatePersonalAccessToken").
+					WithArgs(stored, userID, "deploy bot", tt.wantScopes, nil).
+					WillReturnRows(rowsOf(database.CreatePersonalAccessTokenRow{
+						ID:        uuid.New(),
+						Name:      "deploy bot",
+						Scopes:    []string{scopeChirpsRead, scopeChirpsWrite},
+						CreatedAt: time.Now().UTC(),
+					}))
+			}
+
+			w := serve(cfg.requireAuth(scopeSession, cfg.createTokenHandler), "POST", "/api/tokens", session, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			var resp PersonalAccessToken
+			decodeBody(t, w, &resp)
+			if !strings.HasPrefix(resp.Token, personalAccessTokenPrefix) {
+				t.Errorf("token = %q, want the %q prefix", resp.Token, personalAccessTokenPrefix)
+			}
+			if stored.value != auth.HashToken(resp.Token) {
+				t.Errorf("expected the token to be stored by its hash")
+			}
+		})
+	}
+}
+
+func TestPersonalAccessTokenAuth(t *testing.T) {
+	const token = personalAccessTokenPrefix + "secret"
+	now := time.Now().UTC()
+	pat := database.GetPersonalAccessTokenByHashRow{
+		ID:     uuid.New(),
+		UserID: uuid.New(),
+		Scopes: []string{scopeChirpsRead},
+	}
+
+	tests := []struct {
+		name       string
+		scope      string
+		pat        func(database.GetPersonalAccessTokenByHashRow) database.GetPersonalAccessTokenByHashRow
+		unknown    bool
+		wantStatus int
+		wantHeader string
+	}{
+		{
+			name:       "granted scope",
+			scope:      scopeChirpsRead,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "missing scope",
+			scope:      scopeChirpsWrite,
+			wantStatus: http.StatusForbidden,
+			wantHeader: `error="insufficient_scope", scope="chirps:write"`,
+		},
+		{
+			name:       "login session required",
+			scope:      scopeSession,
+			wantStatus: http.StatusForbidden,
+			wantHeader: `error="insufficient_scope", error_description="login session required"`,
+		},
+		{
+			name:  "revoked",
+			scope: scopeChirpsRead,
+			pat: func(p database.GetPersonalAccessTokenByHashRow) database.GetPersonalAccessTokenByHashRow {
+				p.RevokedAt = sql.NullTime{Time: now, Valid: true}
+				return p
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `error="invalid_token"`,
+		},
+		{
+			name:  "expired",
+			scope: scopeChirpsRead,
+			pat: func(p database.GetPersonalAccessTokenByHashRow) database.GetPersonalAccessTokenByHashRow {
+				p.ExpiresAt = sql.NullTime{Time: now.Add(-time.Minute), Valid: true}
+				return p
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `error="invalid_token"`,
+		},
+		{
+			name:  "owner suspended",
+			scope: scopeChirpsRead,
+			pat: func(p database.GetPersonalAccessTokenByHashRow) database.GetPersonalAccessTokenByHashRow {
+				p.SuspendedAt = sql.NullTime{Time: now, Valid: true}
+				return p
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `error="invalid_token"`,
+		},
+		{
+			name:       "unknown",
+			scope:      scopeChirpsRead,
+			unknown:    true,
+			wantStatus: http.StatusUnauthorized,
+			wantHeader: `error="invalid_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			lookup := mock.ExpectQuery("GetPersonalAccessTokenByHash").WithArgs(auth.HashToken(token))
+			if tt.unknown {
+				lookup.WillReturnError(sql.ErrNoRows)
+			} else {
+				row := pat
+				if tt.pat != nil {
+					row = tt.pat(row)
+				}
+				lookup.WillReturnRows(rowsOf(row))
+			}
+			if tt.wantStatus != http.StatusUnauthorized {
+				mock.ExpectExec("TouchPersonalAccessToken").WithArgs(pat.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			w := serve(cfg.requireAuth(tt.scope, noContent), "GET", "/", token, "")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); !strings.Contains(got, tt.wantHeader) {
+				t.Errorf("WWW-Authenticate = %q, want it to contain %q", got, tt.wantHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+)
+
+const (
+	twoFactorIssuer       = "Chirpy"
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Both are consumed, so each can only be used once.
+func checkSecondFactor(ctx context.Context, q *database.Queries, cred database.TotpCredential, code string) (bool, error) {
+	if step, ok := auth.ValidateTOTP(cred.Secret, code, time.Now()); ok {
+		n, err := q.MarkTOTPStepUsed(ctx, database.MarkTOTPStepUsedParams{
+			Step:   step,
+			UserID: cred.UserID,
+		})
+		return n > 0, err
+	}
+	n, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   cred.UserID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+	})
+	return n > 0, err
+}
+
+// enrollTwoFactorHandler starts (or restarts) enrollment with a new secret.
+// 2FA is not enforced until the user confirms it with a first code.
+func (cfg *apiConfig) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+
+	cred, err := cfg.db.GetTOTPCredential(r.Context(), userID)
+	if err != nil && err != sql.ErrNoRows {
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor settings")
+		return
+	}
+	if err == nil && cred.EnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating secret")
+		return
+	}
+	err = cfg.db.UpsertPendingTOTPCredential(r.Context(), database.UpsertPendingTOTPCredentialParams{
+		UserID: userID,
+		Secret: secret,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving secret")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPURI(secret, twoFactorIssuer, user.Email),
+	})
+}
+
+// confirmTwoFactorHandler turns 2FA on once the user proves their
+// authenticator works, and hands out recovery codes. They are only shown
+// here; the database keeps hashes.
+func (cfg *apiConfig) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	var req twoFactorCodeRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	cred, err := qtx.GetTOTPCredential(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Two-factor enrollment has not been started")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor settings")
+		return
+	}
+	if cred.EnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(cred.Secret, req.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+	_, err = qtx.MarkTOTPStepUsed(r.Context(), database.MarkTOTPStepUsedParams{
+		Step:   step,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+	if err := qtx.EnableTOTPCredential(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating recovery codes")
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(code)
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving recovery codes")
+		return
+	}
+	err = qtx.CreateRecoveryCodes(r.Context(), database.CreateRecoveryCodesParams{
+		CodeHashes: hashes,
+		UserID:     userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving recovery codes")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error enabling two-factor authentication")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string][]string{
+		"recovery_codes": codes,
+	})
+}
+
+func (cfg *apiConfig) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	var req twoFactorCodeRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	cred, err := qtx.GetTOTPCredential(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting two-factor settings")
+		return
+	}
+
+	// A pending enrollment can be dropped without a code; an active one
+	// needs proof the caller still holds the second factor.
+	if cred.EnabledAt.Valid {
+		ok, err := checkSecondFactor(r.Context(), qtx, cred, req.Code)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking code")
+			return
+		}
+		if !ok {
+			respondWithError(w, http.StatusForbidden, "Invalid code")
+			return
+		}
+	}
+
+	if err := qtx.DeleteTOTPCredential(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication")
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error disabling two-factor authentication")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loginTwoFactorHandler finishes a login that loginHandler answered with a
+// challenge token.
+func (cfg *apiConfig) loginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	type loginTwoFactorRequest struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	var req loginTwoFactorRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userID, issuedAt, err := cfg.keys.ValidateChallengeJWT(req.ChallengeToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	cred, err := cfg.db.GetTOTPCredential(r.Context(), userID)
+	if err != nil || !cred.EnabledAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if cred.ChallengesRevokedAt.Valid && !issuedAt.After(cred.ChallengesRevokedAt.Time) {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Codes are throttled per user like passwords are per account, or one
+	// stolen password would allow guessing codes for as long as challenges
+	// can be fetched.
+	wait, lastTry, err := cfg.loginGuard.AttemptSecondFactor(r.Context(), userID.String(), time.Now().UTC())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking login attempts")
+		return
+	}
+	if wait > 0 {
+		respondTooManyAttempts(w, wait)
+		return
+	}
+
+	ok, err := checkSecondFactor(r.Context(), cfg.db, cred, req.Code)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking code")
+		return
+	}
+	if !ok {
+		if lastTry {
+			// Locked out: every challenge the user holds is void, so the
+			// password has to be entered again once the lockout ends.
+			if err := cfg.db.RevokeTOTPChallenges(r.Context(), userID); err != nil {
+				log.Printf("revoking 2FA challenges for user %s: %v", userID, err)
+			}
+		}
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if err := cfg.loginGuard.SecondFactorSuccess(r.Context(), userID.String()); err != nil {
+		log.Printf("clearing 2FA failures for user %s: %v", userID, err)
+	}
+	cfg.startSession(w, r, user)
+}
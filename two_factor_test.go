@@ -0,0 +1,190 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/lockout"
+)
+
+func newTestTOTPCredential(t *testing.T, user database.User) database.TotpCredential {
+	t.Helper()
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	now := time.Now().UTC()
+	return database.TotpCredential{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: now.Add(-time.Hour),
+		EnabledAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+	}
+}
+
+func TestLoginRequiresSecondFactor(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	user := newTestUser()
+	cred := newTestTOTPCredential(t, user)
+
+	mock.ExpectQuery("GetUserByEmail").WithArgs(user.Email).WillReturnRows(rowsOf(user))
+	mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnRows(rowsOf(cred))
+
+	w := serve(cfg.loginHandler, "POST", "/api/login", "", `{"email":"user@example.com","password":"`+testPassword+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+		Token             string `json:"token"`
+		RefreshToken      string `json:"refresh_token"`
+	}
+	decodeBody(t, w, &resp)
+	if !resp.TwoFactorRequired || resp.Token != "" || resp.RefreshToken != "" {
+		t.Errorf("expected only a challenge, got %+v", resp)
+	}
+	// The challenge is not an access token.
+	if _, err := cfg.keys.ValidateJWT(resp.ChallengeToken); err == nil {
+		t.Errorf("expected the challenge token to be refused as an access token")
+	}
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	user := newTestUser()
+	cred := newTestTOTPCredential(t, user)
+	code, err := auth.TOTPCode(cred.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		// challenge returns the token to present, given the server's keys.
+		challenge  func(cfg *apiConfig) string
+		code       string
+		cred       func(database.TotpCredential) database.TotpCredential
+		expect     func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			name: "current code starts a session",
+			code: code,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("MarkTOTPStepUsed").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("GetUserByID").WithArgs(user.ID).WillReturnRows(rowsOf(user))
+				mock.ExpectExec("CreateRefreshToken").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "replayed code",
+			code: code,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("MarkTOTPStepUsed").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown recovery code",
+			code: "aaaa-bbbb",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UseRecoveryCode").
+					WithArgs(user.ID, auth.HashToken(auth.NormalizeRecoveryCode("aaaa-bbbb"))).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "challenge issued before a revocation",
+			code: code,
+			cred: func(c database.TotpCredential) database.TotpCredential {
+				c.ChallengesRevokedAt = sql.NullTime{Time: time.Now().UTC().Add(time.Minute), Valid: true}
+				return c
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "access token is not a challenge",
+			challenge: func(cfg *apiConfig) string {
+				token, _ := cfg.keys.MakeJWT(user.ID, time.Hour)
+				return token
+			},
+			code:       code,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			challenge, err := cfg.keys.MakeChallengeJWT(user.ID, twoFactorChallengeTTL)
+			if err != nil {
+				t.Fatalf("MakeChallengeJWT failed: %v", err)
+			}
+			if tt.challenge != nil {
+				challenge = tt.challenge(cfg)
+			} else {
+				c := cred
+				if tt.cred != nil {
+					c = tt.cred(c)
+				}
+				mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnRows(rowsOf(c))
+			}
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			w := serve(cfg.loginTwoFactorHandler, "POST", "/api/login/2fa", "",
+				`{"challenge_token":"`+challenge+`","code":"`+tt.code+`"}`)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+// TestLoginTwoFactorLockout checks that guessing codes locks the user out
+// and voids the challenges they hold.
+func TestLoginTwoFactorLockout(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	user := newTestUser()
+	cred := newTestTOTPCredential(t, user)
+	challenge, err := cfg.keys.MakeChallengeJWT(user.ID, twoFactorChallengeTTL)
+	if err != nil {
+		t.Fatalf("MakeChallengeJWT failed: %v", err)
+	}
+	// Without backoff every guess gets checked until the lockout.
+	cfg.loginGuard = lockout.NewGuard(lockout.NewMemoryStore(), lockout.Policy{
+		LockoutAfter:    3,
+		LockoutDuration: time.Minute,
+		ResetAfter:      time.Hour,
+	}, lockout.DefaultIPPolicy)
+
+	body := `{"challenge_token":"` + challenge + `","code":"aaaa-bbbb"}`
+	for i := range 3 {
+		mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnRows(rowsOf(cred))
+		mock.ExpectExec("UseRecoveryCode").WillReturnResult(sqlmock.NewResult(0, 0))
+		if i == 2 {
+			mock.ExpectExec("RevokeTOTPChallenges").WithArgs(user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		w := serve(cfg.loginTwoFactorHandler, "POST", "/api/login/2fa", "", body)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: status = %d, want %d: %s", i, w.Code, http.StatusUnauthorized, w.Body)
+		}
+	}
+
+	mock.ExpectQuery("GetTOTPCredential").WithArgs(user.ID).WillReturnRows(rowsOf(cred))
+	w := serve(cfg.loginTwoFactorHandler, "POST", "/api/login/2fa", "", body)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusTooManyRequests, w.Body)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected a Retry-After header")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/mail"
+)
+
+// patchUserHandler applies a sparse update to the caller's account. Fields
+// left out of the body are not touched; changing the password requires the
+// current one and signs out every session.
+func (cfg *apiConfig) patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	caller := authFromContext(r.Context())
+	userID := caller.UserID
+
+	type patchUserRequest struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+	}
+	var req patchUserRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// profile:write covers the public profile; credentials stay behind a
+	// login session.
+	if (req.Email != nil || req.Password != nil) && !caller.hasScope(scopeSession) {
+		respondWithError(w, http.StatusForbidden, "Changing email or password requires a login session")
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	current, err := qtx.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+
+	if req.Password != nil {
+		if *req.Password == "" {
+			respondWithError(w, http.StatusBadRequest, "Password cannot be empty")
+			return
+		}
+		ok, err := auth.CheckPasswordHash(req.CurrentPassword, current.HashedPassword)
+		if err != nil || !ok {
+			respondWithError(w, http.StatusForbidden, "Current password is incorrect")
+			return
+		}
+		hashedPassword, err := auth.HashPassword(*req.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error hashing password")
+			return
+		}
+		err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             userID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating user")
+			return
+		}
+		// As with a password reset, whoever knew the old password may hold a
+		// session or token; sign them all out. The caller's access token
+		// lasts until it expires and they log in again with the new one.
+		if err := qtx.RevokeAllRefreshTokensForUser(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error revoking sessions")
+			return
+		}
+		if err := qtx.RevokeAllPersonalAccessTokensForUser(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error revoking access tokens")
+			return
+		}
+	}
+
+	// Changing the address clears its verification and sends a fresh token.
+	var verifyToken string
+	if req.Email != nil && strings.TrimSpace(*req.Email) != current.Email {
+		email := strings.TrimSpace(*req.Email)
+		if !mail.ValidAddress(email) {
+			respondWithError(w, http.StatusBadRequest, "Invalid email address")
+			return
+		}
+		err = qtx.UpdateUserEmail(r.Context(), database.UpdateUserEmailParams{
+			ID:    userID,
+			Email: email,
+		})
+		if err != nil {
+			if isUniqueViolation(err) {
+				respondWithError(w, http.StatusConflict, "Email already taken")
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error updating user")
+			return
+		}
+		verifyToken, err = issueVerificationToken(r.Context(), qtx, userID, email)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error creating verification token")
+			return
+		}
+	}
+
+	if req.Handle != nil || req.DisplayName != nil || req.Bio != nil {
+		profile := database.UpdateUserProfileParams{
+			ID:          userID,
+			Handle:      current.Handle,
+			DisplayName: current.DisplayName,
+			Bio:         current.Bio,
+		}
+		if req.Handle != nil {
+			profile.Handle = sql.NullString{String: *req.Handle, Valid: *req.Handle != ""}
+		}
+		if req.DisplayName != nil {
+			profile.DisplayName = *req.DisplayName
+		}
+		if req.Bio != nil {
+			profile.Bio = *req.Bio
+		}
+		if msg := validateProfile(profile.Handle.String, profile.DisplayName, profile.Bio); msg != "" {
+			respondWithError(w, http.StatusBadRequest, msg)
+			return
+		}
+		if _, err := qtx.UpdateUserProfile(r.Context(), profile); err != nil {
+			if isUniqueViolation(err) {
+				respondWithError(w, http.StatusConflict, "Handle already taken")
+				return
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error updating user")
+			return
+		}
+	}
+
+	updated, err := qtx.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating user")
+		return
+	}
+	if verifyToken != "" {
+		if err := cfg.sendVerificationEmail(r.Context(), updated.Email, verifyToken); err != nil {
+			log.Printf("sending verification email to user %s: %v", updated.ID, err)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:            updated.ID,
+		CreatedAt:     updated.CreatedAt,
+		UpdatedAt:     updated.UpdatedAt,
+		Email:         updated.Email,
+		IsChirpyRed:   updated.IsChirpyRed,
+		Handle:        updated.Handle.String,
+		DisplayName:   updated.DisplayName,
+		Bio:           updated.Bio,
+		EmailVerified: updated.EmailVerifiedAt.Valid,
+	})
+}
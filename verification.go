@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/akigithub888/chirpy/internal/auth"
+	"github.com/akigithub888/chirpy/internal/database"
+	"github.com/akigithub888/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const verificationTokenTTL = 24 * time.Hour
+
+// issueVerificationToken replaces any outstanding verification tokens for the
+// user with a fresh one bound to email. Only the token's hash is stored.
+func issueVerificationToken(ctx context.Context, q *database.Queries, userID uuid.UUID, email string) (string, error) {
+	if err := q.DeleteEmailVerificationTokens(ctx, userID); err != nil {
+		return "", err
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	err = q.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(verificationTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, email, token string) error {
+	return cfg.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf(
+			"Confirm this email address by sending the token below to POST /api/users/verify.\n\n%s\n\nThe token expires in %d hours.\n",
+			token, int(verificationTokenTTL.Hours()),
+		),
+	})
+}
+
+// requireVerifiedEmail responds with 403 and returns false when the user has
+// not verified their email address yet.
+func (cfg *apiConfig) requireVerifiedEmail(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Email address not verified")
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	type verifyEmailRequest struct {
+		Token string `json:"token"`
+	}
+	var req verifyEmailRequest
+	decoder := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := decoder.Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Token == "" {
+		respondWithError(w, http.StatusBadRequest, "Token required")
+		return
+	}
+
+	tx, err := cfg.dbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	record, err := qtx.ConsumeEmailVerificationToken(r.Context(), auth.HashToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+	if time.Now().UTC().After(record.ExpiresAt) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+
+	// The token is bound to the address it was sent to; if the user has since
+	// changed their email, it no longer proves anything.
+	n, err := qtx.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    record.UserID,
+		Email: record.Email,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error verifying email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) resendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID := authFromContext(r.Context()).UserID
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error getting user")
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email address already verified")
+		return
+	}
+
+	token, err := issueVerificationToken(r.Context(), cfg.db, user.ID, user.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error creating verification token")
+		return
+	}
+	if err := cfg.sendVerificationEmail(r.Context(), user.Email, token); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error sending verification email")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}